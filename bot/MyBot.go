@@ -4,6 +4,8 @@ import (
 	"fmt"
 	"math/rand"
 	"os"
+	"runtime/pprof"
+	"strconv"
 	"strings"
 	"time"
 
@@ -27,6 +29,10 @@ func main() {
 	var longest_turn time.Duration
 	var longest_turn_number int
 
+	var stats *hal.StatsWriter
+	pprof_turns := make(map[int]bool)
+	snapshot_turns := make(map[int]bool)
+
 	start_time := time.Now()
 
 	defer func() {
@@ -36,7 +42,14 @@ func main() {
 			frame.Log("Last known hash: %s", frame.Hash())
 			frame.Log("Longest turn (%d) took %v", longest_turn_number, longest_turn)
 			frame.Log("Real-world time elapsed: %v", time.Now().Sub(start_time))
+			frame.LogProfiles()
+			frame.LogTimings()
+			ai.LogSafety(frame)
+			ai.LogEndgame(frame)
 			logging.StopLog()
+			logging.StopSinks()
+			ai.StopTrace()
+			stats.Close()
 			logging.StopFlog()
 		}
 	}()
@@ -48,10 +61,25 @@ func main() {
 
 	// Both of these fail harmlessly if the directory isn't there:
 	logging.StartLog(fmt.Sprintf("logs/log-%v.txt", true_pid))
-	logging.StartFlog(fmt.Sprintf("flogs/flog-%v-%v.json", frame.Constants.GameSeed, true_pid))
+	logging.StartFlog(fmt.Sprintf("flogs/flog-%v-%v.jsonl", frame.Constants.GameSeed, true_pid))
+
+	if config.LogJSON {
+		logging.StartJSONLog(fmt.Sprintf("logs/log-%v.jsonl", true_pid))
+	}
+
+	if level, ok := logging.ParseLevel(config.LogLevel); ok {
+		logging.SetLevel(level)
+	}
+
+	for _, subsystem := range strings.Split(config.Silence, ",") {
+		if subsystem != "" {
+			logging.Silence(subsystem)
+		}
+	}
 
 	frame.PreParse()				// Reads the map data.
 
+	config.SetTimeMargin(frame.Width())
 	config.SetGenMin(frame.Width(), frame.Players())
 
 	logging.Log("--------------------------------------------------------------------------------")
@@ -66,16 +94,68 @@ func main() {
 	logging.Log("./halite.py --width %d --height %d -s %v %s",
 		frame.Width(), frame.Height(), frame.Constants.GameSeed, strings.Join(player_strings, " "))
 
-	logging.Log("GenMin is %v", config.GenMin)
+	if config.OldSpawn {
+		logging.Log("GenMin is %v", config.GenMin)
+	}
+
+	logging.Log("Time margin is %v", config.TimeMargin)
 
 	// -------------------------------------------------------------------------------
 
-	if config.SimTest {
+	if config.SimReplay != "" && hal.ReplayCompressed(config.SimReplay) {
+		if err := hal.CheckZstd(); err != nil {			// Say so now, not after simulating the whole game
+			logging.Log("Not writing simulator replay: %v", err)
+			config.SimReplay = ""
+		}
+	}
+
+	if config.SimTest || config.SimReplay != "" {
+
+		var replay *hal.Replay
+		if config.SimReplay != "" {
+			replay = hal.NewReplay(frame, NAME + " " + VERSION)
+		}
+
 		logging.Suppress()
-		prediction_hash, prediction_ground := sim_check(frame)
+		prediction_hash, prediction_ground := sim_check(frame, replay)
 		logging.Allow()
 		logging.Log("Simulator predicts final hash %v", prediction_hash)
 		logging.Log("Simulator predicts ground halite %v on turn N-1", prediction_ground)
+
+		if replay != nil {
+			err := replay.Write(config.SimReplay)
+			if err != nil {
+				logging.Log("Couldn't write simulator replay: %v", err)
+			}
+		}
+	}
+
+	if config.Trace {				// After the sim test, so only the real game is traced
+		ai.StartTrace(fmt.Sprintf("logs/trace-%v.jsonl", true_pid))
+	}
+
+	if config.Stats != "" {
+		var err error
+		stats, err = hal.NewStatsWriter(fmt.Sprintf("logs/stats-%v.%s", true_pid, config.Stats))
+		if err != nil {
+			logging.Log("Not writing stats: %v", err)
+		}
+	}
+
+	if config.Timings {
+		hal.StartTimings()
+	}
+
+	for _, s := range strings.Split(config.PprofTurns, ",") {
+		if t, err := strconv.Atoi(s); err == nil {
+			pprof_turns[t] = true
+		}
+	}
+
+	for _, s := range strings.Split(config.SnapshotTurns, ",") {
+		if t, err := strconv.Atoi(s); err == nil {
+			snapshot_turns[t] = true
+		}
 	}
 
 	fmt.Printf("%s %s\n", NAME, VERSION)
@@ -83,10 +163,26 @@ func main() {
 	for {
 		frame.Parse()
 
+		if snapshot_turns[frame.Turn()] {			// Before the AI runs, so loading it reproduces this turn
+			err := frame.SaveSnapshot(fmt.Sprintf("logs/snapshot-%v-%03d.json", true_pid, frame.Turn()))
+			if err != nil {
+				frame.Log("Couldn't save snapshot: %v", err)
+			}
+		}
+
+		var profile_file *os.File
+		if pprof_turns[frame.Turn()] {
+			profile_file = start_cpu_profile(fmt.Sprintf("logs/cpu-%v-%03d.pprof", true_pid, frame.Turn()))
+		}
+
 		if config.RemakeTest {
 			frame = frame.Remake()
 		}
 
+		if config.MapTest {
+			frame.CheckMaps()
+		}
+
 		if config.Crash {
 			if rand.Intn(100) == 40 {
 				fmt.Printf("g g\n")
@@ -95,9 +191,16 @@ func main() {
 			}
 		}
 
-		ai.Step(frame, true_pid, true)
+		ai.Step(frame, true_pid, true, frame.NewDeadline(config.TimeMargin))
 		frame.Send()
 
+		if profile_file != nil {
+			pprof.StopCPUProfile()
+			profile_file.Close()
+		}
+
+		stats.Write(frame, time.Now().Sub(frame.ParseTime))
+
 		if time.Now().Sub(frame.ParseTime) > longest_turn {
 			longest_turn = time.Now().Sub(frame.ParseTime)
 			longest_turn_number = frame.Turn()
@@ -105,22 +208,53 @@ func main() {
 	}
 }
 
-func sim_check(real_frame *hal.Frame) (string, int) {
+func sim_check(real_frame *hal.Frame, replay *hal.Replay) (string, int) {
 
 	// Returns the final hash that the real bot will see,
 	// if the real bot is matched only against itself...
+	// The replay, if not nil, records the simulated game.
 
 	frame := real_frame.Remake()
 
 	for {
-		if frame.Turn() == frame.Constants.MAX_TURNS - 1 {
+		last_turn := frame.Turn() == frame.Constants.MAX_TURNS - 1
+
+		if last_turn && replay == nil {
 			return frame.Hash(), frame.GroundHalite()
 		}
 
 		for pid := 0; pid < frame.Players(); pid++ {
-			ai.Step(frame, pid, true)
+			ai.Step(frame, pid, true, nil)
 		}
 
-		frame = frame.SimGen()
+		next := frame.SimGen()
+
+		if replay != nil {
+			replay.Record(frame, next)
+		}
+
+		if last_turn {				// The replay needs the last turn's moves and deposits too
+			return frame.Hash(), frame.GroundHalite()
+		}
+
+		frame = next
+	}
+}
+
+func start_cpu_profile(filename string) *os.File {
+
+	// Returns nil on failure. Otherwise the caller must close the file after
+	// StopCPUProfile(), which is what flushes it.
+
+	outfile, err := os.Create(filename)
+	if err != nil {
+		return nil
 	}
+
+	if pprof.StartCPUProfile(outfile) != nil {
+		outfile.Close()
+		return nil
+	}
+
+	return outfile
 }
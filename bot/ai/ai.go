@@ -2,7 +2,6 @@ package ai
 
 import (
 	"math/rand"
-	"sort"
 
 	"../config"
 	hal "../core"
@@ -12,10 +11,14 @@ const (
 	DROPOFF_SPACING = 12
 	NICE_THRESHOLD = 8000
 	BLOCKER_IGNORE_DIST = 3
-	DASH_CRITICAL = 5
+	ENEMY_RISK_THRESHOLD = 0.2
+	TARGET_FALLBACK_RADIUS = 8
 )
 
-func Step(frame *hal.Frame, pid int, allow_build bool) {
+func Step(frame *hal.Frame, pid int, allow_build bool, deadline *hal.Deadline) {
+
+	// The deadline may be nil (no limit). Expensive stages check it and settle
+	// for their best result so far when it expires.
 
 	frame.SetPid(pid)		// Always have this first.
 
@@ -23,30 +26,74 @@ func Step(frame *hal.Frame, pid int, allow_build bool) {
 
 	my_ships := frame.MyShips()
 
+	defer hal.Time("Step")()
+
+	tracer.Begin()
+
+	done := hal.Time("NewTurn")
 	for _, ship := range my_ships {
 		NewTurn(ship)
 	}
+	done()
 
-	target_book := hal.Make2dBoolArray(frame.Width(), frame.Height())
+	done = hal.Time("ScheduleEndgame")
+	ScheduleEndgame(frame, my_ships)
+	done()
 
-	for _, ship := range my_ships {
-		SetTarget(ship, target_book)
+	if allow_build {
+		done = hal.Time("PlanDropoff")
+		PlanDropoff(frame, my_ships, deadline)
+		done()
 	}
 
-	TargetSwaps(my_ships, 4)
+	target_book := hal.Make2dBoolArray(frame.Width(), frame.Height())
+
+	done = hal.Time("PlanRoutes")
+	PlanRoutes(frame, my_ships, target_book, deadline)
+	done()
+
+	done = hal.Time("SetTargets")
+	SetTargets(frame, my_ships, target_book, deadline)
+	done()
 
+	done = hal.Time("TargetSwaps")
+	TargetSwaps(my_ships, 4, deadline)
+	done()
+
+	done = hal.Time("SetDesires")
 	for _, ship := range my_ships {
 		SetDesires(ship)
+		AvoidDanger(ship)
 	}
+	done()
 
+	if config.Attack && frame.Players() == 2 {
+		done = hal.Time("Attacks")
+		Attacks(frame, my_ships)
+		done()
+	}
+
+	done = hal.Time("Resolve")
 	move_book := Resolve(frame, my_ships)
+	done()
+
+	done = hal.Time("Lookahead")
+	Lookahead(frame, my_ships, move_book, deadline)
+	done()
 
 	if allow_build {
+		done = hal.Time("MaybeBuild")
 		MaybeBuild(frame, my_ships, move_book)
+		done()
 	}
 
+	SpendRouteTurns(my_ships)
+
+	tracer.Write(frame, my_ships)
+
 	for _, ship := range my_ships {
 		FlogTarget(ship)
+		FlogRoute(ship)
 	}
 
 	return
@@ -57,57 +104,34 @@ func MaybeBuild(frame *hal.Frame, my_ships []*hal.Ship, move_book *MoveBook) {
 	budget := frame.MyBudget()
 
 	// -------------------------------------------
-
-	var possible_constructs []*hal.Ship
+	// The planned dropoff, if its ship has arrived; otherwise hold back what it will need.
 
 	for _, ship := range my_ships {
 
-		if ship.Dist(ship.NearestDropoff()) < DROPOFF_SPACING {
+		if ship.HasBuildSite() == false {
 			continue
 		}
 
-		if frame.WealthMap().Values[ship.X][ship.Y] < NICE_THRESHOLD {
-			continue
+		if ship.SamePlace(ship.BuildSite()) {
+			halite_at := frame.HaliteAtFast(ship.X, ship.Y)
+			if ship.Halite + halite_at + budget >= frame.Constants.DROPOFF_COST {
+				ship.Command = "c"
+				tracer.Note(ship, "build", "arrived at planned dropoff site")
+				ship.Logger("dropoffs").Info("building planned dropoff", "score", int(SiteScore(frame, ship.Point())))
+				budget -= frame.Constants.DROPOFF_COST
+				budget += ship.Halite + halite_at
+				continue
+			}
 		}
 
-		if frame.HaliteAtFast(ship.X, ship.Y) == 0 {		// Cheap way to avoid building on enemy dropoff / factory
-			continue
-		}
-
-		possible_constructs = append(possible_constructs, ship)
-	}
-
-	sort.Slice(possible_constructs, func (a, b int) bool {
-
-		return	frame.WealthMap().Values[possible_constructs[a].X][possible_constructs[a].Y] >		// Reverse
-				frame.WealthMap().Values[possible_constructs[b].X][possible_constructs[b].Y]
-	})
-
-	for _, ship := range possible_constructs {
-		halite_at := frame.HaliteAtFast(ship.X, ship.Y)
-		if ship.Halite + halite_at + budget >= frame.Constants.DROPOFF_COST {
-			ship.Command = "c"
-			frame.Log("Ship %d building dropoff (wmap: %d)", ship.Sid, frame.WealthMap().Values[ship.X][ship.Y])
-			budget -= frame.Constants.DROPOFF_COST
-			budget += ship.Halite + halite_at
-			break
-		}
+		budget -= BuildReserve(frame, ship)
 	}
 
 	// -------------------------------------------
 
 	factory := frame.MyFactory()
-	willing := true
 
-	if float64(frame.GroundHalite()) / float64(frame.InitialGroundHalite()) < config.GenMin {
-		willing = false
-	}
-/*
-	if frame.Turn() >= frame.Constants.MAX_TURNS / 2 {
-		willing = false
-	}
-*/
-	if budget >= frame.Constants.NEW_ENTITY_ENERGY_COST && move_book.Booker(factory) == nil && willing {
+	if budget >= frame.Constants.NEW_ENTITY_ENERGY_COST && move_book.Booker(factory) == nil && WantToSpawn(frame) {
 		frame.SetGenerate(true)
 		budget -= frame.Constants.NEW_ENTITY_ENERGY_COST
 	}
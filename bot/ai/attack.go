@@ -0,0 +1,116 @@
+package ai
+
+// Optional attack layer for 2 player games: an empty ship of ours next to a
+// heavily-laden enemy may ram it, when the exchange is in our favour.
+
+import (
+	"sort"
+
+	hal "../core"
+)
+
+const (
+	ATTACK_MIN_CARGO = 500			// Enemy must carry at least this much
+	ATTACK_MAX_CARGO = 100			// Our attacker must carry at most this much
+	ATTACK_MIN_GAIN = 300
+)
+
+type attack struct {
+	attacker		*hal.Ship
+	victim			*hal.Ship
+	gain			int
+}
+
+func Attacks(frame *hal.Frame, my_ships []*hal.Ship) {
+
+	// Sets the Desires of any ship that should attack. Must come after SetDesires().
+
+	var attacks []attack
+
+	for _, attacker := range my_ships {
+
+		if attacker.Halite > ATTACK_MAX_CARGO || attacker.Halite < attacker.MoveCost() {
+			continue
+		}
+
+		for _, victim := range frame.EnemyShips() {
+
+			if victim.Halite < ATTACK_MIN_CARGO || attacker.Dist(victim) != 1 {
+				continue
+			}
+
+			if frame.PlayerCanDropoffAt(victim.Owner, victim) {		// Halite would go straight to the victim's owner
+				continue
+			}
+
+			gain := AttackGain(frame, attacker, victim)
+
+			if gain >= ATTACK_MIN_GAIN {
+				attacks = append(attacks, attack{attacker, victim, gain})
+			}
+		}
+	}
+
+	sort.Slice(attacks, func(a, b int) bool {
+		return attacks[a].gain > attacks[b].gain
+	})
+
+	busy := make(map[*hal.Ship]bool)
+
+	for _, a := range attacks {
+
+		if busy[a.attacker] || busy[a.victim] {
+			continue
+		}
+
+		busy[a.attacker] = true
+		busy[a.victim] = true
+
+		for _, move := range []string{"e", "w", "s", "n"} {
+			if a.attacker.LocationAfterMove(move) == a.victim.Point() {
+				a.attacker.Desires = []string{move}		// A single desire can't be overridden by our other ships
+				tracer.Note(a.attacker, "attack", "desires set to ram ship %d (gain %d)", a.victim.Sid, a.gain)
+				break
+			}
+		}
+
+		a.attacker.Logger("attack").Info("attacking", "victim", a.victim.Sid, "cargo", a.victim.Halite, "gain", a.gain)
+	}
+}
+
+func AttackGain(frame *hal.Frame, attacker, victim *hal.Ship) int {
+
+	// Both sides lose a ship, so in a 2 player game only the halite matters:
+	// what each side loses, plus the dropped halite for whoever will likely pick it up.
+	// Who's nearest is decided by the other ships, since these two will be gone.
+
+	dropped := attacker.Halite + victim.Halite
+
+	friendly_dist := nearest_other(frame, victim, attacker.Owner, attacker)
+	enemy_dist := nearest_other(frame, victim, victim.Owner, victim)
+
+	gain := victim.Halite - attacker.Halite
+
+	if friendly_dist < enemy_dist {
+		gain += dropped
+	} else if friendly_dist > enemy_dist {
+		gain -= dropped
+	}
+
+	return gain
+}
+
+func nearest_other(frame *hal.Frame, pos hal.XYer, pid int, exclude *hal.Ship) int {
+
+	ret := 9999
+
+	for _, ship := range frame.Ships(pid) {
+		if ship != exclude {
+			if dist := ship.Dist(pos); dist < ret {
+				ret = dist
+			}
+		}
+	}
+
+	return ret
+}
@@ -0,0 +1,153 @@
+package ai
+
+// Dropoff planning: pick the best future dropoff site anywhere on the map,
+// designate a ship to go there, and keep enough budget back to build when it
+// arrives. The plan is stored on the designated ship, so it survives between
+// turns the same way the other AI fields do, and dies with the ship.
+
+import (
+	hal "../core"
+)
+
+const (
+	PLAN_RADIUS = 6					// Radius of the wealth sum used to score sites
+	PLAN_MIN_SHIPS = 6
+	PLAN_MIN_TURNS_LEFT = 100
+	PLAN_MAX_DIST = 20				// Sites further than this from our dropoffs are out of reach
+	PLAN_ENEMY_PENALTY = 0.5		// Multiplier for sites in enemy territory or near enemy dropoffs
+)
+
+func PlanDropoff(frame *hal.Frame, my_ships []*hal.Ship, deadline *hal.Deadline) {
+
+	// Check the existing plan, if any...
+
+	var builder *hal.Ship
+
+	for _, ship := range my_ships {
+		if ship.HasBuildSite() {
+			if builder == nil {
+				builder = ship
+			} else {
+				ship.ClearBuildSite()		// Shouldn't happen
+			}
+		}
+	}
+
+	if builder != nil {
+		if builder.FinalDash || SiteScore(frame, builder.BuildSite()) < PlanThreshold(frame) / 2 {
+			builder.Logger("dropoffs").Info("abandoning dropoff plan", "x", builder.BuildSite().X, "y", builder.BuildSite().Y)
+			builder.ClearBuildSite()
+		}
+		return
+	}
+
+	if len(my_ships) < PLAN_MIN_SHIPS || frame.Constants.MAX_TURNS - frame.Turn() < PLAN_MIN_TURNS_LEFT {
+		return
+	}
+
+	// Make a new plan...
+
+	var best_site hal.Point
+	best_score := 0.0
+
+	for x := 0; x < frame.Width(); x++ {
+
+		if deadline.Expired() {
+			frame.Logger("dropoffs").Warn("PlanDropoff() out of time", "columns", x)
+			break
+		}
+
+		for y := 0; y < frame.Height(); y++ {
+			score := SiteScore(frame, hal.Point{x, y})
+			if score > best_score {
+				best_site = hal.Point{x, y}
+				best_score = score
+			}
+		}
+	}
+
+	if best_score < PlanThreshold(frame) {
+		return
+	}
+
+	for _, ship := range my_ships {
+		if ship.FinalDash == false {
+			if builder == nil || ship.Dist(best_site) < builder.Dist(best_site) {
+				builder = ship
+			}
+		}
+	}
+
+	if builder == nil {
+		return
+	}
+
+	builder.SetBuildSite(best_site)
+	builder.ClearRoute()
+	builder.Logger("dropoffs").Info("sent to build dropoff", "x", best_site.X, "y", best_site.Y, "score", int(best_score), "dist", builder.Dist(best_site))
+}
+
+func SiteScore(frame *hal.Frame, pos hal.Point) float64 {
+
+	// Halite near the site, discounted if the enemy is likely to get there first.
+	// Zero if the site is unusable.
+
+	dropoff_dist := frame.DropoffDistMap().Values[pos.X][pos.Y]
+
+	if dropoff_dist < DROPOFF_SPACING || dropoff_dist > PLAN_MAX_DIST {
+		return 0
+	}
+
+	near_enemy_dropoff := false
+
+	for _, dropoff := range frame.EnemyDropoffs() {
+		dist := dropoff.Dist(pos)
+		if dist == 0 {
+			return 0
+		}
+		if dist < DROPOFF_SPACING {
+			near_enemy_dropoff = true
+		}
+	}
+
+	score := float64(frame.WealthTable().Sum(pos.X, pos.Y, plan_radius(frame)))
+
+	if frame.ContestMap().Values[pos.X][pos.Y] > 0 {
+		score *= PLAN_ENEMY_PENALTY
+	}
+
+	if near_enemy_dropoff {
+		score *= PLAN_ENEMY_PENALTY
+	}
+
+	return score
+}
+
+func PlanThreshold(frame *hal.Frame) float64 {
+
+	// NICE_THRESHOLD was tuned for the WealthMap's radius; scale it by area.
+
+	r := plan_radius(frame)
+	wr := hal.WEALTH_MAP_RADIUS
+
+	return float64(NICE_THRESHOLD) * float64(2 * r * r + 2 * r + 1) / float64(2 * wr * wr + 2 * wr + 1)
+}
+
+func plan_radius(frame *hal.Frame) int {
+	if PLAN_RADIUS > frame.WealthTable().MaxRadius {
+		return frame.WealthTable().MaxRadius
+	}
+	return PLAN_RADIUS
+}
+
+func BuildReserve(frame *hal.Frame, builder *hal.Ship) int {
+
+	// What the builder will still need from the budget when it arrives.
+
+	reserve := frame.Constants.DROPOFF_COST - builder.Halite - frame.HaliteAt(builder.BuildSite())
+
+	if reserve < 0 {
+		return 0
+	}
+	return reserve
+}
@@ -0,0 +1,88 @@
+package ai
+
+// End-game scheduling. Near the end every ship must get home, but each dropoff
+// can only take about ENDGAME_ENTRANCES ships per turn (one per neighbouring
+// cell), so the ships heading to each dropoff are given arrival slots, working
+// back from the final turn. Each ship keeps mining until the last turn it can
+// leave and still make its slot; then it goes into its final dash. Resolve()
+// gives dashing ships near a dropoff priority over ships still mining there.
+//
+// Nothing here is kept between turns except the ships' own FinalDash flags, so
+// simulated frames can run it freely.
+
+import (
+	"sort"
+
+	hal "../core"
+)
+
+const (
+	ENDGAME_ENTRANCES = 4
+	ENDGAME_SLACK = 2			// Spare turns allowed for blocked moves and such
+)
+
+func ScheduleEndgame(frame *hal.Frame, my_ships []*hal.Ship) {
+
+	turns_left := frame.Constants.MAX_TURNS - frame.Turn()
+
+	// Group ships by their nearest dropoff...
+
+	groups := make(map[hal.Point][]*hal.Ship)
+
+	for _, ship := range my_ships {
+		dropoff := ship.NearestDropoff()
+		groups[dropoff.Point()] = append(groups[dropoff.Point()], ship)
+	}
+
+	for _, ships := range groups {
+
+		// The nearest ships get the latest slots, since they can afford to mine longest.
+		// Ties broken by sid to keep things stable from turn to turn.
+
+		sort.Slice(ships, func(a, b int) bool {
+			dist_a := ships[a].Dist(ships[a].NearestDropoff())
+			dist_b := ships[b].Dist(ships[b].NearestDropoff())
+			if dist_a == dist_b {
+				return ships[a].Sid < ships[b].Sid
+			}
+			return dist_a < dist_b
+		})
+
+		for rank, ship := range ships {
+
+			dist := ship.Dist(ship.NearestDropoff())
+			slot := rank / ENDGAME_ENTRANCES
+
+			if ship.FinalDash == false && dist + slot + ENDGAME_SLACK >= turns_left {
+				ship.FinalDash = true
+				ship.Returning = true
+				ship.ClearRoute()
+				if dist > turns_left && ship.Halite > 0 {
+					ship.Logger("endgame").Warn("can't get home in time", "dist", dist, "halite", ship.Halite)
+				}
+			}
+		}
+	}
+}
+
+func LogEndgame(frame *hal.Frame) {
+
+	// On the final turn, whatever our ships carry, except what this turn's
+	// moves deliver, is lost when the game ends.
+
+	if frame.Turn() != frame.Constants.MAX_TURNS - 1 {
+		return
+	}
+
+	late_ships := 0
+	late_halite := 0
+
+	for _, ship := range frame.MyShips() {
+		if ship.Halite > 0 && frame.PlayerCanDropoffAt(ship.Owner, ship.LocationAfterMove(ship.Command)) == false {
+			late_ships++
+			late_halite += ship.Halite
+		}
+	}
+
+	frame.Logger("endgame").Info("halite lost to late arrivals", "ships", late_ships, "halite", late_halite)
+}
@@ -0,0 +1,67 @@
+package ai
+
+// Inspiration. A ship with enough enemy ships nearby is inspired: it mines at
+// the inspired extraction ratio, gets a bonus on everything it mines, and pays
+// less to move. Cells are valued at their expected yield, given the chance
+// (from the InspirationForecast) that they'll be inspired when the ship gets
+// there. In games with more than 2 players, lightly-laden ships also seek
+// inspiration on purpose: the chance is weighted up for them, so they settle
+// next to enemy clusters rather than in quiet corners.
+
+import (
+	"../config"
+	hal "../core"
+)
+
+const (
+	SEEK_MAX_CARGO = 500			// Ships carrying more than this don't seek inspiration
+	SEEK_WEIGHT = 2.0
+)
+
+func InspirationWeight(ship *hal.Ship, pos hal.XYer, turns int) float64 {
+
+	// The chance <pos> is inspired <turns> turns from now, weighted up if the
+	// ship is seeking inspiration. So it may exceed 1.
+
+	frame := ship.Frame
+
+	p := frame.InspirationForecast().Prob(pos, turns)
+
+	if Seeking(ship) {
+		p *= SEEK_WEIGHT
+	}
+
+	return p
+}
+
+func Seeking(ship *hal.Ship) bool {
+	return config.NoInspire == false && ship.Frame.Players() > 2 && ship.Halite <= SEEK_MAX_CARGO
+}
+
+func ExpectedHalite(ship *hal.Ship, x, y, halite int) int {
+
+	// What <halite> at (x, y) is worth to the ship, counting inspiration.
+	// Exactly <halite> if there's no chance of inspiration.
+
+	frame := ship.Frame
+	c := frame.Constants
+
+	w := InspirationWeight(ship, hal.Point{x, y}, ship.Dist(hal.Point{x, y}))
+
+	if w == 0 {
+		return halite
+	}
+
+	p := w
+	if p > 1 {
+		p = 1
+	}
+
+	// Extraction ratio and move cost use the true chance; the bonus uses the weighted one...
+
+	extract := float64(c.EXTRACT_RATIO) * ((1 - p) / float64(c.EXTRACT_RATIO) + p / float64(c.INSPIRED_EXTRACT_RATIO))
+	bonus := 1 + w * c.INSPIRED_BONUS_MULTIPLIER
+	move_saving := p * (1 / float64(c.MOVE_COST_RATIO) - 1 / float64(c.INSPIRED_MOVE_COST_RATIO))
+
+	return int(float64(halite) * (extract * bonus + move_saving))
+}
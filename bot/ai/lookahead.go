@@ -0,0 +1,272 @@
+package ai
+
+// Short-horizon search for contested situations. After the normal pipeline has
+// chosen everyone's moves, ships near enemies or in crowds at our dropoffs try
+// alternative joint moves in the simulator for a few turns, and the best joint
+// move (by the halite value of the resulting position) replaces the normal one.
+// Enemies are assumed to make their most likely move each turn, our other
+// ships to sit still after the first turn.
+
+import (
+	"sort"
+
+	"../config"
+	hal "../core"
+)
+
+const (
+	LOOKAHEAD_MAX_SHIPS = 2
+	LOOKAHEAD_MOVES = 2				// Desires considered per ship, besides its chosen move and staying put
+	LOOKAHEAD_TURNS = 2
+	LOOKAHEAD_ENEMY_DIST = 2
+	LOOKAHEAD_CROWD = 3				// Our ships within 2 of a dropoff that count as a crowd
+	LOOKAHEAD_CARGO_VALUE = 0.5		// Cargo isn't banked yet, so is worth less than budget
+	LOOKAHEAD_MIN_GAIN = 200		// A short search is myopic, so only trust it on big differences
+)
+
+func Lookahead(frame *hal.Frame, my_ships []*hal.Ship, book *MoveBook, deadline *hal.Deadline) {
+
+	if config.NoLookahead {
+		return
+	}
+
+	contested := ContestedShips(frame, my_ships)
+
+	if len(contested) == 0 {
+		return
+	}
+
+	// Each contested ship's options, its chosen move always first...
+
+	var options [][]string
+
+	for _, ship := range contested {
+
+		opts := []string{ship.Command}
+		seen := map[string]bool{ship.Command: true}
+
+		if ship.Command == "" {			// "" and "o" are the same thing here
+			seen["o"] = true
+		}
+
+		for _, desire := range ship.Desires {
+			if len(opts) > LOOKAHEAD_MOVES {
+				break
+			}
+			if seen[desire] == false {
+				opts = append(opts, desire)
+				seen[desire] = true
+			}
+		}
+
+		if seen["o"] == false {
+			opts = append(opts, "o")
+		}
+
+		options = append(options, opts)
+	}
+
+	// Try every combination, odometer style. The first is the normal pipeline's choice.
+
+	indices := make([]int, len(contested))
+	moves := make([]string, len(contested))
+
+	var best []string
+	var best_value float64
+	tried := 0
+
+	for {
+
+		if deadline.Expired() {
+			frame.Logger("lookahead").Warn("Lookahead() out of time", "tried", tried)
+			break
+		}
+
+		for i, n := range indices {
+			moves[i] = options[i][n]
+		}
+
+		value := lookahead_value(frame, contested, moves)
+		tried++
+
+		if tried == 1 {
+			value += LOOKAHEAD_MIN_GAIN			// The normal choice gets a head start
+		}
+
+		if best == nil || value > best_value {
+			best = append([]string(nil), moves...)
+			best_value = value
+		}
+
+		// Next combination...
+
+		i := 0
+		for i < len(indices) {
+			indices[i]++
+			if indices[i] < len(options[i]) {
+				break
+			}
+			indices[i] = 0
+			i++
+		}
+		if i == len(indices) {
+			break
+		}
+	}
+
+	if best == nil {
+		return
+	}
+
+	// The simulation has our other ships sitting still, but the resolver may have
+	// moved them. If the best joint move takes a cell one of them has booked, keep
+	// the normal choice (which is consistent with the book) for everyone.
+
+	is_contested := make(map[*hal.Ship]bool)
+	for _, ship := range contested {
+		is_contested[ship] = true
+	}
+
+	for i, ship := range contested {
+
+		if best[i] == ship.Command {
+			continue
+		}
+
+		booker := book.Booker(ship.LocationAfterMove(best[i]))
+
+		if booker != nil && booker.Owner == ship.Owner && is_contested[booker] == false {
+			ship.Logger("lookahead").Info("not changing move, cell is booked", "to", best[i], "booker", booker.Sid)
+			return
+		}
+	}
+
+	for i, ship := range contested {
+
+		if best[i] == ship.Command {
+			continue
+		}
+
+		ship.Logger("lookahead").Info("changing move", "from", ship.Command, "to", best[i])
+
+		old_loc := ship.LocationAfterMove(ship.Command)
+		if book.Booker(old_loc) == ship {
+			book.ClearBook(old_loc)
+		}
+
+		old_command := ship.Command
+
+		ship.Move(best[i])
+		book.SetBook(ship, ship.LocationAfterMove(best[i]))
+
+		tracer.Note(ship, "lookahead", "simulation preferred this to %q", old_command)
+	}
+}
+
+func ContestedShips(frame *hal.Frame, my_ships []*hal.Ship) []*hal.Ship {
+
+	var ret []*hal.Ship
+
+	enemies := frame.EnemyShips()
+
+	for _, ship := range my_ships {
+
+		if ship.Command == "c" || ship.Halite < ship.MoveCost() {
+			continue
+		}
+
+		contested := false
+
+		for _, enemy := range enemies {
+			if ship.Dist(enemy) <= LOOKAHEAD_ENEMY_DIST {
+				contested = true
+				break
+			}
+		}
+
+		if contested == false {
+			dropoff := ship.NearestDropoff()
+			if ship.Dist(dropoff) <= 1 {
+				crowd := 0
+				for _, other := range my_ships {
+					if other.Dist(dropoff) <= 2 {
+						crowd++
+					}
+				}
+				contested = crowd >= LOOKAHEAD_CROWD
+			}
+		}
+
+		if contested {
+			ret = append(ret, ship)
+		}
+	}
+
+	// Most at stake first...
+
+	sort.SliceStable(ret, func(a, b int) bool {
+		return ret[a].Halite > ret[b].Halite
+	})
+
+	if len(ret) > LOOKAHEAD_MAX_SHIPS {
+		ret = ret[:LOOKAHEAD_MAX_SHIPS]
+	}
+
+	return ret
+}
+
+func lookahead_value(frame *hal.Frame, contested []*hal.Ship, moves []string) float64 {
+
+	g := frame.RemakeThrowaway()			// Nothing here needs the maps inherited
+
+	for i, ship := range contested {
+		g.Sid(ship.Sid).Move(moves[i])
+	}
+
+	for turn := 0; turn < LOOKAHEAD_TURNS; turn++ {
+		for _, enemy := range g.EnemyShips() {
+			enemy.Move(likely_move(enemy))
+		}
+		g = g.SimGen()
+	}
+
+	return PositionValue(g)
+}
+
+func likely_move(ship *hal.Ship) string {
+
+	odds := ship.MoveOdds()
+
+	ret := "o"
+	for _, move := range []string{"e", "w", "s", "n"} {
+		if odds[move] > odds[ret] {
+			ret = move
+		}
+	}
+
+	return ret
+}
+
+func PositionValue(frame *hal.Frame) float64 {
+
+	// Halite value of the current pid's position: budget, cargo (discounted, but
+	// for ships in their final dash worth it all if they can still get it home,
+	// and nothing if not) and ships (worth their cost at the start of the game,
+	// nothing at the end).
+
+	turns_left := frame.Constants.MAX_TURNS - frame.Turn()
+	ship_value := float64(frame.Constants.NEW_ENTITY_ENERGY_COST) * float64(turns_left) / float64(frame.Constants.MAX_TURNS)
+
+	value := float64(frame.MyBudget())
+
+	for _, ship := range frame.MyShips() {
+		value += ship_value
+		if ship.FinalDash == false {
+			value += float64(ship.Halite) * LOOKAHEAD_CARGO_VALUE
+		} else if ship.Dist(ship.NearestDropoff()) <= turns_left {
+			value += float64(ship.Halite)
+		}
+	}
+
+	return value
+}
@@ -23,6 +23,24 @@ func SetDesires(ship *hal.Ship) {
 		return
 	}
 
+	// Maybe we're on our way to build a dropoff, and shouldn't stop...
+
+	if ship.HasBuildSite() {
+		DesireNav(ship)
+		return
+	}
+
+	// Maybe we're following a route, in which case we mine at its first step and nowhere else...
+
+	if ship.HasRoute() {
+		if RouteStepOK(ship) || ShouldMine(ship.Frame, ship.Halite, ship.HaliteAt(), ship.TargetHalite()) {
+			ship.Desires = []string{"o"}
+		} else {
+			DesireNav(ship)
+		}
+		return
+	}
+
 	// Maybe we're happy where we are...
 
 	if ShouldMine(ship.Frame, ship.Halite, ship.HaliteAt(), ship.TargetHalite()) {
@@ -51,18 +51,17 @@ func Resolve(frame *hal.Frame, my_ships []*hal.Ship) *MoveBook {
 
 	if config.NoAntiEnemyCollision == false {
 		if frame.Players() == 4 {
-			for _, ship := range frame.EnemyShips() {
-				if frame.DropoffDistMap().Values[ship.X][ship.Y] > BLOCKER_IGNORE_DIST {
-					book.SetBook(ship, ship)
-				}
-			}
+			BookEnemies(frame, book)
 		}
 	}
 
+	QueueAtEntrances(frame, my_ships)
+
 	for _, ship := range my_ships {
 		if ship.Desires[0] == "o" {
 			ship.Move("o")
 			book.SetBook(ship, ship)
+			tracer.Note(ship, "stay", "first desire is to stay")
 		}
 	}
 
@@ -79,12 +78,13 @@ func Resolve(frame *hal.Frame, my_ships []*hal.Ship) *MoveBook {
 
 			if ship.TargetIsDropoff() && ship.Dist(ship.Target()) == 1 && ship.FinalDash {
 				ship.Move(ship.Desires[0])
+				tracer.Note(ship, "final dash", "next to dropoff, ignoring the book")
 				continue
 			}
 
 			// Normal case...
 
-			for _, desire := range ship.Desires {
+			for n, desire := range ship.Desires {
 
 				new_loc := ship.LocationAfterMove(desire)
 				booker := book.Booker(new_loc)
@@ -92,6 +92,7 @@ func Resolve(frame *hal.Frame, my_ships []*hal.Ship) *MoveBook {
 				if booker == nil {
 					ship.Move(desire)
 					book.SetBook(ship, new_loc)
+					tracer.Note(ship, "phase 1", "desire %d was unbooked (cycle %d)", n, cycle)
 					break
 				} else {
 					if len(booker.Desires) == 1 {		// The booker has no choice but to do what it's doing
@@ -100,10 +101,12 @@ func Resolve(frame *hal.Frame, my_ships []*hal.Ship) *MoveBook {
 					if booker.Owner != frame.Pid() {
 						continue
 					}
-					if booker.Halite < ship.Halite {
+					if booker.Halite < ship.Halite || (ship.FinalDash && booker.FinalDash == false) {
 						ship.Move(desire)
 						book.SetBook(ship, new_loc)
 						booker.Move("")
+						tracer.Note(ship, "phase 1", "desire %d overrode ship %d (cycle %d)", n, booker.Sid, cycle)
+						tracer.Note(booker, "phase 1", "overridden by heavier or dashing ship %d (cycle %d)", ship.Sid, cycle)
 						break
 					}
 				}
@@ -128,7 +131,7 @@ func Resolve(frame *hal.Frame, my_ships []*hal.Ship) *MoveBook {
 			continue
 		}
 
-		for _, desire := range ship.Desires {
+		for n, desire := range ship.Desires {
 
 			new_loc := ship.LocationAfterMove(desire)
 			booker := book.Booker(new_loc)
@@ -136,6 +139,7 @@ func Resolve(frame *hal.Frame, my_ships []*hal.Ship) *MoveBook {
 			if booker == nil {
 				ship.Move(desire)
 				book.SetBook(ship, new_loc)
+				tracer.Note(ship, "dropoff", "stalled on dropoff, desire %d was unbooked", n)
 				break
 			}
 		}
@@ -151,7 +155,7 @@ func Resolve(frame *hal.Frame, my_ships []*hal.Ship) *MoveBook {
 				continue
 			}
 
-			for _, desire := range ship.Desires {
+			for n, desire := range ship.Desires {
 
 				new_loc := ship.LocationAfterMove(desire)
 				booker := book.Booker(new_loc)
@@ -159,6 +163,7 @@ func Resolve(frame *hal.Frame, my_ships []*hal.Ship) *MoveBook {
 				if booker == nil {
 					ship.Move(desire)
 					book.SetBook(ship, new_loc)
+					tracer.Note(ship, "phase 2", "stalled, desire %d was unbooked (cycle %d)", n, cycle)
 					break
 				}
 			}
@@ -186,38 +191,126 @@ func Resolve(frame *hal.Frame, my_ships []*hal.Ship) *MoveBook {
 	return book
 }
 
+func QueueAtEntrances(frame *hal.Frame, my_ships []*hal.Ship) {
+
+	// Near the end, dashing ships crowd the cells next to each dropoff. A dashing
+	// ship close to its dropoff waits its turn rather than wandering off when the
+	// way in is taken; and any ship still mining in its way is made willing to
+	// move aside (phase 1 lets dashing ships override ships that aren't).
+
+	for _, ship := range my_ships {
+
+		if ship.FinalDash == false || ship.TargetIsDropoff() == false {
+			continue
+		}
+
+		dropoff := ship.NearestDropoff()
+		dist := ship.Dist(dropoff)
+
+		if dist < 1 || dist > 2 {
+			continue
+		}
+
+		var desires []string
+
+		for _, desire := range ship.Desires {
+
+			new_loc := ship.LocationAfterMove(desire)
+
+			if dropoff.Dist(new_loc) >= dist {
+				continue
+			}
+
+			desires = append(desires, desire)
+
+			miner := frame.ShipAt(new_loc)
+
+			if miner != nil && miner.Owner == ship.Owner && miner.FinalDash == false && len(miner.Desires) == 1 && miner.Halite >= miner.MoveCost() {
+				miner.Desires = []string{"o", "e", "w", "s", "n"}
+				tracer.Note(miner, "entrance", "mining in the way of dashing ship %d", ship.Sid)
+			}
+		}
+
+		if len(desires) > 0 {
+			ship.Desires = append(desires, "o")
+		}
+	}
+}
+
+func BookEnemies(frame *hal.Frame, book *MoveBook) {
+
+	// Book every cell (away from our dropoffs) that some enemy is reasonably likely
+	// to occupy next turn, using whichever enemy is most likely to be there.
+
+	risk := frame.EnemyRiskMap()
+	best_odds := make(map[hal.Point]float64)
+
+	for _, ship := range frame.EnemyShips() {
+
+		odds := ship.MoveOdds()
+
+		for _, move := range []string{"o", "e", "w", "s", "n"} {		// Not map order, so ties are broken the same way every time
+
+			p, ok := odds[move]
+			if ok == false {
+				continue
+			}
+
+			loc := ship.LocationAfterMove(move)
+
+			if risk.Values[loc.X][loc.Y] < ENEMY_RISK_THRESHOLD {
+				continue
+			}
+
+			if frame.DropoffDistMap().Values[loc.X][loc.Y] <= BLOCKER_IGNORE_DIST {
+				continue
+			}
+
+			if p > best_odds[loc] {
+				best_odds[loc] = p
+				book.SetBook(ship, loc)
+			}
+		}
+	}
+}
+
 func PreventCollision(innocent *hal.Ship, book *MoveBook, log_string string) {
 
 	// If called correctly, the innocent ship is motionless but has
 	// some incoming ship that's going to collide with it.
 
 	if innocent.OnDropoff() && innocent.FinalDash {
-		innocent.Frame.Log("PreventCollision(Ship %d) -- ship was on dropoff and in final dash mode", innocent.Sid)
+		innocent.Logger("resolver").Debug("PreventCollision() -- ship was on dropoff and in final dash mode")
 		return
 	}
 
 	if innocent.Command != "" && innocent.Command != "o" {
-		innocent.Frame.Log("PreventCollision(Ship %d) -- this ship was moving", innocent.Sid)
+		innocent.Logger("resolver").Debug("PreventCollision() -- this ship was moving")
 		return
 	}
 
 	villain := book.Booker(innocent)
 
 	if villain == nil {
-		innocent.Frame.Log("PreventCollision(Ship %d) -- no incoming ship noted in the book", innocent.Sid)
+		innocent.Logger("resolver").Debug("PreventCollision() -- no incoming ship noted in the book")
 		return
 	}
 
 	if villain == innocent {
-		innocent.Frame.Log("PreventCollision(Ship %d) -- this ship was already the booker", innocent.Sid)
+		innocent.Logger("resolver").Debug("PreventCollision() -- this ship was already the booker")
 		return
 	}
 
+	if villain.Owner != innocent.Owner {
+		return		// Booked by a predicted enemy; nothing we can cancel.
+	}
+
 	innocent.Move("")
 	book.ClearBook(innocent)
 
-	villain.Frame.Log("(%s) Cancelling move of ship %d, move was %s", log_string, villain.Sid, villain.Command)
+	villain.Logger("resolver").Info("cancelling move", "reason", log_string, "move", villain.Command)
 	villain.Move("")
+	tracer.Note(villain, log_string, "cancelled by collision prevention, ship %d couldn't get out of the way", innocent.Sid)
 
 	// Do we need to recurse?
 
@@ -0,0 +1,269 @@
+package ai
+
+// Mining routes. Rather than chasing a single cell, a ship plans a series of
+// cells to mine, with the number of turns to spend on each, worked out from the
+// extraction rule. Steps are chosen greedily by halite per turn, where the turn
+// cost includes any extra distance home, so routes tend to drift back towards
+// a dropoff as the ship fills up. Steps are added while they raise the rate of
+// the whole trip. The route is stored on the ship and survives between turns;
+// each turn the first step is either mined or travelled to (mining on the way
+// by the usual rule, since a good cell underneath is worth a turn anyway).
+
+import (
+	"fmt"
+
+	"../config"
+	"../logging"
+	hal "../core"
+)
+
+const (
+	ROUTE_RADIUS = 8				// Each step is looked for within this distance of the previous one
+	ROUTE_MAX_STEPS = 5
+	ROUTE_MAX_MINE_TURNS = 8		// Per step
+	ROUTE_FILL = 0.8				// Fraction of MAX_ENERGY a route aims to fill
+)
+
+func PlanRoutes(frame *hal.Frame, my_ships []*hal.Ship, target_book [][]bool, deadline *hal.Deadline) {
+
+	if config.NoRoutes {
+		return
+	}
+
+	// Existing routes are booked first, so new routes keep out of their way...
+
+	for _, ship := range my_ships {
+		for _, step := range ship.Route() {
+			target_book[step.X][step.Y] = true
+		}
+	}
+
+	for _, ship := range my_ships {
+
+		if ship.HasRoute() || ship.Returning || ship.HasBuildSite() {
+			continue
+		}
+
+		if deadline.Expired() {
+			frame.Logger("routes").WarnOnce("PlanRoutes() out of time, some ships will use single targets")
+			return
+		}
+
+		PlanRoute(ship, target_book)
+	}
+}
+
+func PlanRoute(ship *hal.Ship, target_book [][]bool) bool {
+
+	// Returns false if no route was worth making. Books the cells of any route made.
+
+	frame := ship.Frame
+	dropoff_dist := frame.DropoffDistMap().Values
+
+	ignore_threshold := IgnoreThreshold(frame)
+	full := int(float64(frame.Constants.MAX_ENERGY) * ROUTE_FILL)
+
+	pos := ship.Point()
+	cargo := ship.Halite
+
+	var route []hal.RouteStep
+	used := make(map[hal.Point]bool)
+
+	// Steps are judged by the halite per turn of the whole trip so far, counting
+	// the trip home from the last step...
+
+	total_gain := 0
+	total_time := dropoff_dist[pos.X][pos.Y]
+	elapsed := 0				// Turns until the ship leaves the last step
+
+	for len(route) < ROUTE_MAX_STEPS && cargo < full {
+
+		var best_step hal.RouteStep
+		best_rate := 0.0
+		best_gain := 0
+		best_mined := 0
+		best_time := 0
+		best_elapsed := 0
+
+		for dx := -ROUTE_RADIUS; dx <= ROUTE_RADIUS; dx++ {
+
+			for dy := -ROUTE_RADIUS; dy <= ROUTE_RADIUS; dy++ {
+
+				dist := hal.Abs(dx) + hal.Abs(dy)
+				if dist > ROUTE_RADIUS {
+					continue
+				}
+
+				x := hal.Mod(pos.X + dx, frame.Width())
+				y := hal.Mod(pos.Y + dy, frame.Height())
+
+				if target_book[x][y] || used[hal.Point{x, y}] {
+					continue
+				}
+
+				halite := frame.HaliteAtFast(x, y)
+
+				if halite < ignore_threshold {
+					continue
+				}
+
+				// Moving further from home costs those turns again on the way back...
+
+				home := dropoff_dist[x][y] - dropoff_dist[pos.X][pos.Y]
+
+				inspiration := InspirationWeight(ship, hal.Point{x, y}, elapsed + dist)
+
+				for turns := 1; turns <= ROUTE_MAX_MINE_TURNS; turns++ {
+
+					mined, left := MineYield(frame, halite, cargo, turns, inspiration)
+					gain := mined - LeavingCost(frame, left, inspiration)
+
+					time := total_time + dist + turns + home
+					if time < 1 {
+						time = 1
+					}
+
+					rate := float64(total_gain + gain) / float64(time)
+
+					if rate > best_rate {
+						best_step = hal.RouteStep{hal.Point{x, y}, turns}
+						best_rate = rate
+						best_gain = gain
+						best_mined = mined
+						best_time = time
+						best_elapsed = elapsed + dist + turns
+					}
+
+					if cargo + mined >= frame.Constants.MAX_ENERGY {
+						break
+					}
+				}
+			}
+		}
+
+		if best_rate <= 0 || (len(route) > 0 && best_rate < float64(total_gain) / float64(total_time)) {
+			break
+		}
+
+		route = append(route, best_step)
+		used[best_step.Point] = true
+		cargo += best_mined
+		total_gain += best_gain
+		total_time = best_time
+		elapsed = best_elapsed
+		pos = best_step.Point
+	}
+
+	if len(route) == 0 {
+		return false
+	}
+
+	for _, step := range route {
+		target_book[step.X][step.Y] = true
+	}
+
+	ship.SetRoute(route)
+	return true
+}
+
+func MineYield(frame *hal.Frame, halite, cargo, turns int, inspiration float64) (int, int) {
+
+	// What a ship carrying <cargo> gets from <turns> turns at a cell holding <halite>,
+	// and what's left in the cell. Same rule as the engine, with the inspiration
+	// bonus scaled by <inspiration> (see InspirationWeight).
+
+	c := frame.Constants
+
+	exrat := c.EXTRACT_RATIO
+	if inspiration >= 0.5 {
+		exrat = c.INSPIRED_EXTRACT_RATIO
+	}
+
+	mined := 0
+
+	for n := 0; n < turns; n++ {
+
+		amount := (halite + exrat - 1) / exrat
+
+		if cargo + mined + amount >= c.MAX_ENERGY {
+			amount = c.MAX_ENERGY - cargo - mined
+		}
+
+		halite -= amount
+
+		amount += int(float64(amount) * c.INSPIRED_BONUS_MULTIPLIER * inspiration)
+
+		if cargo + mined + amount >= c.MAX_ENERGY {
+			amount = c.MAX_ENERGY - cargo - mined
+		}
+
+		mined += amount
+	}
+
+	return mined, halite
+}
+
+func LeavingCost(frame *hal.Frame, halite int, inspiration float64) int {
+	if inspiration >= 0.5 {
+		return halite / frame.Constants.INSPIRED_MOVE_COST_RATIO
+	}
+	return halite / frame.Constants.MOVE_COST_RATIO
+}
+
+func UpdateRoute(ship *hal.Ship) {
+
+	// Drop finished steps from the front of the route, or the whole route if
+	// the halite it was planned around has gone.
+
+	frame := ship.Frame
+
+	for ship.HasRoute() {
+		step := ship.Route()[0]
+		if step.Turns > 0 && frame.HaliteAt(step) >= HappyThreshold(frame) {
+			break
+		}
+		ship.PopRoute()
+	}
+
+	for i, step := range ship.Route() {
+		if i > 0 && frame.HaliteAt(step) < IgnoreThreshold(frame) {
+			ship.ClearRoute()
+			return
+		}
+	}
+}
+
+func RouteStepOK(ship *hal.Ship) bool {
+
+	// Whether the ship is at the first step of its route, and so should mine.
+
+	return ship.HasRoute() && ship.SamePlace(ship.Route()[0])
+}
+
+func SpendRouteTurns(my_ships []*hal.Ship) {
+
+	// After all moves are final: ships staying on their route step have spent a turn there.
+
+	for _, ship := range my_ships {
+		if (ship.Command == "o" || ship.Command == "") && RouteStepOK(ship) {
+			ship.SpendRouteTurn()
+		}
+	}
+}
+
+func init() {
+	logging.DefineFlogLayer("routes", "Mining routes", 0, 0, "", "")
+}
+
+func FlogRoute(ship *hal.Ship) {
+
+	var prev hal.XYer = ship
+
+	for i, step := range ship.Route() {
+		ship.Frame.FlogIn(step.X, step.Y, "routes", fmt.Sprintf("Ship %d route step %d: %d turns", ship.Sid, i, step.Turns), "")
+		if ship.SamePlace(step) == false || i > 0 {
+			ship.Frame.FlogArrow(prev, step, "routes", "PaleGreen")
+		}
+		prev = step
+	}
+}
@@ -0,0 +1,118 @@
+package ai
+
+// Laden ships try to keep out of cells an enemy could reach next turn.
+
+import (
+	"sort"
+
+	"../config"
+	hal "../core"
+)
+
+const (
+	SAFETY_MIN_CARGO = 300			// Ships carrying less than this don't bother
+	SAFETY_THRESHOLD = 50.0			// Danger above this is worth avoiding
+	SAFETY_CONTEST_BONUS = 0.5		// Extra danger in enemy territory, where they get the wreckage
+	SAFETY_RAMMER_BONUS = 0.5		// Extra danger from players whose profile says they ram
+)
+
+func Danger(frame *hal.Frame, ship *hal.Ship, pos hal.XYer) float64 {
+
+	// Roughly, the halite we'd expect to lose by being at <pos> next turn:
+	// the chance an enemy is there, times how much more we carry than the
+	// cheapest enemy that could be there, scaled up in enemy territory and
+	// when that enemy belongs to a known rammer.
+
+	x := hal.Mod(pos.GetX(), frame.Width())
+	y := hal.Mod(pos.GetY(), frame.Height())
+
+	if frame.EnemyDistMap().Values[x][y] > 1 {
+		return 0
+	}
+
+	if frame.PlayerCanDropoffAt(ship.Owner, hal.Point{x, y}) {		// Wreckage at our own dropoff is ours
+		return 0
+	}
+
+	var cheapest *hal.Ship
+
+	for _, enemy := range frame.EnemyShips() {
+		if enemy.Dist(hal.Point{x, y}) <= 1 {
+			if cheapest == nil || enemy.Halite < cheapest.Halite {
+				cheapest = enemy
+			}
+		}
+	}
+
+	if cheapest == nil || cheapest.Halite >= ship.Halite {
+		return 0
+	}
+
+	danger := frame.EnemyRiskMap().Values[x][y] * float64(ship.Halite - cheapest.Halite)
+
+	if frame.ContestMap().Values[x][y] > 0 {
+		danger *= 1 + SAFETY_CONTEST_BONUS
+	}
+
+	if profile := frame.Profile(cheapest.Owner); profile != nil && profile.Rammer() {
+		danger *= 1 + SAFETY_RAMMER_BONUS
+	}
+
+	return danger
+}
+
+func AvoidDanger(ship *hal.Ship) {
+
+	// Move dangerous desires to the back, otherwise keeping their order.
+
+	if config.NoAntiEnemyCollision || ship.FinalDash || ship.Halite < SAFETY_MIN_CARGO || ship.Halite < ship.MoveCost() {
+		return
+	}
+
+	frame := ship.Frame
+
+	// A ship happy to sit still should still flee if sitting still is dangerous...
+
+	if len(ship.Desires) == 1 && ship.Desires[0] == "o" {
+		if Danger(frame, ship, ship) <= SAFETY_THRESHOLD {
+			return
+		}
+		ship.Desires = []string{"o", "e", "w", "s", "n"}
+	}
+
+	dangerous := make(map[string]bool)
+
+	for _, desire := range ship.Desires {
+		if Danger(frame, ship, ship.LocationAfterMove(desire)) > SAFETY_THRESHOLD {
+			dangerous[desire] = true
+		}
+	}
+
+	if len(dangerous) == 0 {
+		return
+	}
+
+	original := ship.Desires[0]
+
+	sort.SliceStable(ship.Desires, func(a, b int) bool {
+		return dangerous[ship.Desires[a]] == false && dangerous[ship.Desires[b]]
+	})
+
+	if ship.Desires[0] != original {
+		ship.SetDodged(ship.LocationAfterMove(original))
+		ship.Logger("safety").Info("avoiding danger", "cargo", ship.Halite, "desires", ship.Desires)
+	}
+}
+
+func LogSafety(frame *hal.Frame) {
+
+	// Collisions are only counted as avoided if, next turn, an enemy was in the
+	// cell we'd originally have moved to.
+
+	profile := frame.Profile(frame.Pid())
+	if profile == nil {
+		return
+	}
+
+	frame.Logger("safety").Info("game summary", "avoided_collisions", profile.AvoidedCollisions, "course_changes", profile.Dodges, "lost_to_enemies", profile.EnemyCollisions)
+}
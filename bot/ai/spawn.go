@@ -0,0 +1,75 @@
+package ai
+
+// Whether a new ship is worth its cost, judged by how much extra halite the
+// game's ships will collect before the end because it exists.
+//
+// The model: every ship on the map (ours or not) removes, each turn, about
+// SPAWN_EFFICIENCY / EXTRACT_RATIO of an average cell's halite. So with n ships
+// on a map of a cells, the ground halite g decays like g * exp(-n * c * t / a),
+// and over t turns the fleet takes f(n) = g * (1 - exp(-n * c * t / a)). A new
+// ship is worth f(n + 1) - f(n), which falls off faster than the average share
+// f(n + 1) / (n + 1) since it mostly takes halite other ships would have got.
+
+import (
+	"math"
+
+	"../config"
+	hal "../core"
+)
+
+const (
+	SPAWN_EFFICIENCY = 0.5			// Fraction of an average cell's extraction a ship achieves per turn, all things considered
+	SPAWN_MARGIN = 1.0				// Required ratio of expected return to cost
+)
+
+func ExpectedShipReturn(frame *hal.Frame) float64 {
+
+	area := float64(frame.Width() * frame.Height())
+	ships := float64(frame.TotalShips())
+	ground := float64(frame.GroundHalite())
+
+	// A new ship loses some turns getting out to the halite and back at the end...
+
+	turns := float64(frame.Constants.MAX_TURNS - frame.Turn() - 2 * MeanDropoffDist(frame))
+
+	if turns <= 0 {
+		return 0
+	}
+
+	c := SPAWN_EFFICIENCY / float64(frame.Constants.EXTRACT_RATIO)
+
+	fleet_take := func(n float64) float64 {
+		return ground * (1 - math.Exp(-n * c * turns / area))
+	}
+
+	return fleet_take(ships + 1) - fleet_take(ships)
+}
+
+func MeanDropoffDist(frame *hal.Frame) int {
+
+	total := 0
+
+	for x := 0; x < frame.Width(); x++ {
+		for y := 0; y < frame.Height(); y++ {
+			total += frame.DropoffDistMap().Values[x][y]
+		}
+	}
+
+	return total / (frame.Width() * frame.Height())
+}
+
+func WantToSpawn(frame *hal.Frame) bool {
+
+	if config.OldSpawn {
+		return float64(frame.GroundHalite()) / float64(frame.InitialGroundHalite()) >= config.GenMin
+	}
+
+	expected := ExpectedShipReturn(frame)
+
+	if expected < float64(frame.Constants.NEW_ENTITY_ENERGY_COST) * SPAWN_MARGIN {
+		frame.Logger("spawn").InfoOnce("stopped wanting to spawn", "expected", int(expected))
+		return false
+	}
+
+	return true
+}
@@ -2,6 +2,7 @@ package ai
 
 import (
 	"fmt"
+	"../logging"
 	hal "../core"
 )
 
@@ -12,33 +13,65 @@ func NewTurn(ship *hal.Ship) {
 
 	ship.ClearTarget()
 
-	if ship.Dist(ship.NearestDropoff()) > ship.Frame.Constants.MAX_TURNS - ship.Frame.Turn() - DASH_CRITICAL {
-		ship.FinalDash = true
-	}
+	UpdateRoute(ship)
+
+	// FinalDash is set by ScheduleEndgame(), once set it stays set.
+	// A ship with a route keeps mining until the route is done or it's full.
 
-	if ship.FinalDash || ShouldReturn(ship.Halite) || (ship.Returning && ship.OnDropoff() == false) {
+	should_return := ShouldReturn(ship.Halite) && ship.HasRoute() == false
+	full := ship.Halite >= int(float64(ship.Frame.Constants.MAX_ENERGY) * ROUTE_FILL)
+
+	if ship.FinalDash || should_return || full || (ship.Returning && ship.OnDropoff() == false) {
 		ship.Returning = true
+		ship.ClearRoute()
 	} else {
 		ship.Returning = false
 	}
 }
 
-func SetTarget(ship *hal.Ship, target_book [][]bool) {
+func SetTarget(ship *hal.Ship, target_book [][]bool, deadline *hal.Deadline) {
+
+	if ship.HasBuildSite() {
+		ship.SetTarget(ship.BuildSite())
+		target_book[ship.Target().X][ship.Target().Y] = true
+		return
+	}
 
 	if ship.Returning {
 		ship.SetTarget(ship.NearestDropoff())
 		return
 	}
 
+	if ship.HasRoute() {
+		ship.SetTarget(ship.Route()[0])				// PlanRoutes() did the booking
+		ship.Score = 0
+		return
+	}
+
 	ship.ClearTarget()
 
 	frame := ship.Frame
 	width := frame.Width()
 	height := frame.Height()
 
-	for x := 0; x < width; x++ {
+	// Out of time, we search just a small box around the ship...
+
+	startx, endx := 0, width
+	starty, endy := 0, height
+
+	if deadline.Expired() {
+		startx, endx = ship.X - TARGET_FALLBACK_RADIUS, ship.X + TARGET_FALLBACK_RADIUS + 1
+		starty, endy = ship.Y - TARGET_FALLBACK_RADIUS, ship.Y + TARGET_FALLBACK_RADIUS + 1
+		frame.Logger("targets").WarnOnce("SetTarget() out of time, using small searches")
+	}
+
+	for i := startx; i < endx; i++ {
+
+		x := hal.Mod(i, width)
 
-		for y := 0; y < height; y++ {
+		for j := starty; j < endy; j++ {
+
+			y := hal.Mod(j, height)
 
 			if target_book[x][y] {
 				continue
@@ -50,8 +83,7 @@ func SetTarget(ship *hal.Ship, target_book [][]bool) {
 				continue
 			}
 
-			dist := ship.Dist(hal.Point{x, y})
-			score := HaliteDistScore(halite, dist)
+			score := CellScore(ship, x, y, halite)
 
 			if ship.TargetOK() == false || score > ship.Score {
 				ship.SetTarget(hal.Point{x, y})
@@ -66,8 +98,7 @@ func SetTarget(ship *hal.Ship, target_book [][]bool) {
 	// against its score might lead us to reject a box we should pick.
 
 	if ship.TargetOK() == false {
-		ship.SetTarget(ship)										// Default - my own square
-		ship.Score = HaliteDistScore(ship.HaliteAt(), 0)
+		SetDefaultTarget(ship)
 	}
 
 	// Set the book for this ship. Note that for dropoff targets,
@@ -76,7 +107,20 @@ func SetTarget(ship *hal.Ship, target_book [][]bool) {
 	target_book[ship.Target().X][ship.Target().Y] = true
 }
 
-func TargetSwaps(my_ships []*hal.Ship, cycles int) {
+func SetDefaultTarget(ship *hal.Ship) {
+	ship.SetTarget(ship)											// Default - my own square
+	ship.Score = CellScore(ship, ship.X, ship.Y, ship.HaliteAt())
+}
+
+func CellScore(ship *hal.Ship, x, y, halite int) float32 {
+
+	// How much <ship> would like to mine at (x, y). Must be safe to call concurrently,
+	// so the frame's InspirationForecast must already have been made.
+
+	return HaliteDistScore(ExpectedHalite(ship, x, y, halite), ship.Dist(hal.Point{x, y}))
+}
+
+func TargetSwaps(my_ships []*hal.Ship, cycles int, deadline *hal.Deadline) {
 
 	for cycle := 0; cycle < cycles; cycle++ {
 
@@ -84,21 +128,23 @@ func TargetSwaps(my_ships []*hal.Ship, cycles int) {
 
 		for i, ship_a := range my_ships {
 
-			if ship_a.TargetIsDropoff() {
+			if deadline.Expired() {
+				ship_a.Frame.Logger("targets").Warn("TargetSwaps() out of time", "cycle", cycle)
+				return
+			}
+
+			if ship_a.TargetIsDropoff() || ship_a.HasRoute() {
 				continue
 			}
 
 			for _, ship_b := range my_ships[i + 1:] {
 
-				if ship_b.TargetIsDropoff() {
+				if ship_b.TargetIsDropoff() || ship_b.HasRoute() {
 					continue
 				}
 
-				a_dist_b := ship_a.Dist(ship_b.Target())
-				b_dist_a := ship_b.Dist(ship_a.Target())
-
-				alt_score_a := HaliteDistScore(ship_b.TargetHalite(), a_dist_b)
-				alt_score_b := HaliteDistScore(ship_a.TargetHalite(), b_dist_a)
+				alt_score_a := CellScore(ship_a, ship_b.Target().X, ship_b.Target().Y, ship_b.TargetHalite())
+				alt_score_b := CellScore(ship_b, ship_a.Target().X, ship_a.Target().Y, ship_a.TargetHalite())
 
 				if alt_score_a + alt_score_b > ship_a.Score + ship_b.Score {
 
@@ -121,7 +167,14 @@ func TargetSwaps(my_ships []*hal.Ship, cycles int) {
 	}
 }
 
+func init() {
+	logging.DefineFlogLayer("targets", "Ship targets", 0, 0, "", "")
+}
+
 func FlogTarget(ship *hal.Ship) {
-	ship.Frame.Flog(ship.X, ship.Y, fmt.Sprintf("Target: %d %d - Dist: %d", ship.Target().X, ship.Target().Y, ship.Dist(ship.Target())), "")
-	ship.Frame.Flog(ship.Target().X, ship.Target().Y, "", "LemonChiffon")
+	ship.Frame.FlogIn(ship.X, ship.Y, "targets", fmt.Sprintf("Target: %d %d - Dist: %d", ship.Target().X, ship.Target().Y, ship.Dist(ship.Target())), "")
+	ship.Frame.FlogIn(ship.Target().X, ship.Target().Y, "targets", "", "LemonChiffon")
+	if ship.SamePlace(ship.Target()) == false {
+		ship.Frame.FlogArrow(ship, ship.Target(), "targets", "LemonChiffon")
+	}
 }
@@ -0,0 +1,153 @@
+package ai
+
+// Concurrent version of the SetTarget() loop. Each ship's scan of the whole map
+// runs in parallel, producing its best few candidates in the same order the
+// sequential scan would prefer them (score, then scan order). The target book
+// is then resolved one ship at a time, in the usual order, by taking each
+// ship's first unbooked candidate -- which is exactly what SetTarget() would
+// have picked. If all of a ship's candidates got booked, it falls back to
+// SetTarget() itself, so the results are always identical.
+
+import (
+	"runtime"
+	"sort"
+	"sync"
+
+	"../config"
+	hal "../core"
+)
+
+const (
+	TARGET_CANDIDATES = 32
+)
+
+type target_candidate struct {
+	point			hal.Point
+	score			float32
+	order			int				// Position in the sequential scan, for tie-breaks
+}
+
+func SetTargets(frame *hal.Frame, my_ships []*hal.Ship, target_book [][]bool, deadline *hal.Deadline) {
+
+	if config.SerialTargets || len(my_ships) < 2 || deadline.Expired() {
+		for _, ship := range my_ships {
+			SetTarget(ship, target_book, deadline)
+		}
+		return
+	}
+
+	k := len(my_ships)
+	if k > TARGET_CANDIDATES {
+		k = TARGET_CANDIDATES
+	}
+
+	// Anything lazily cached must be made before the goroutines start...
+
+	ignore_threshold := IgnoreThreshold(frame)
+	frame.InspirationForecast()
+
+	candidates := make([][]target_candidate, len(my_ships))
+
+	jobs := make(chan int, len(my_ships))
+	for i, ship := range my_ships {
+		if ship.HasBuildSite() == false && ship.Returning == false && ship.HasRoute() == false {
+			jobs <- i
+		}
+	}
+	close(jobs)
+
+	var wg sync.WaitGroup
+
+	for n := 0; n < runtime.NumCPU(); n++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range jobs {
+				candidates[i] = target_candidates(my_ships[i], ignore_threshold, k)
+			}
+		}()
+	}
+
+	wg.Wait()
+
+	// Resolve in order...
+
+	for i, ship := range my_ships {
+
+		if ship.HasBuildSite() || ship.Returning || ship.HasRoute() {
+			SetTarget(ship, target_book, deadline)
+			continue
+		}
+
+		ship.ClearTarget()
+
+		for _, c := range candidates[i] {
+			if target_book[c.point.X][c.point.Y] == false {
+				ship.SetTarget(c.point)
+				ship.Score = c.score
+				break
+			}
+		}
+
+		if ship.TargetOK() == false {
+			if len(candidates[i]) < k {				// We saw every acceptable cell and all are booked
+				SetDefaultTarget(ship)
+			} else {
+				SetTarget(ship, target_book, deadline)
+				continue							// SetTarget() did the booking
+			}
+		}
+
+		target_book[ship.Target().X][ship.Target().Y] = true
+	}
+}
+
+func target_candidates(ship *hal.Ship, ignore_threshold int, k int) []target_candidate {
+
+	// The <k> best cells for the ship, ignoring the target book, best first.
+
+	frame := ship.Frame
+	width := frame.Width()
+	height := frame.Height()
+
+	ret := make([]target_candidate, 0, k + 1)
+
+	for x := 0; x < width; x++ {
+
+		for y := 0; y < height; y++ {
+
+			halite := frame.HaliteAtFast(x, y)
+
+			if halite < ignore_threshold {
+				continue
+			}
+
+			c := target_candidate{hal.Point{x, y}, CellScore(ship, x, y, halite), x * height + y}
+
+			if len(ret) == k && better_candidate(ret[k - 1], c) {
+				continue
+			}
+
+			index := sort.Search(len(ret), func(n int) bool {
+				return better_candidate(c, ret[n])
+			})
+
+			ret = append(ret, target_candidate{})
+			copy(ret[index + 1:], ret[index:])
+			ret[index] = c
+
+			if len(ret) > k {
+				ret = ret[:k]
+			}
+		}
+	}
+
+	return ret
+}
+
+func better_candidate(a, b target_candidate) bool {
+	if a.score != b.score {
+		return a.score > b.score
+	}
+	return a.order < b.order
+}
@@ -0,0 +1,94 @@
+package ai
+
+import (
+	"os"
+	"testing"
+
+	hal "../core"
+)
+
+func TestConcurrentTargetsMatchSerial(t *testing.T) {
+
+	// On every 10th turn of a recorded game, for every player, the concurrent
+	// SetTargets() must give each ship the same target and score as calling
+	// SetTarget() one ship at a time.
+
+	infile, err := os.Open("../core/testdata/game-32-4p.txt")
+	if err != nil {
+		t.Fatalf("%v", err)
+	}
+	defer infile.Close()
+
+	hal.SetInput(infile)
+	defer hal.SetInput(os.Stdin)
+
+	frame := hal.NewGame()
+	frame.PrePreParse()
+	frame.PreParse()
+
+	checked := 0
+
+	for try_parse(frame) {
+
+		if frame.Turn() % 10 != 0 {
+			continue
+		}
+
+		for pid := 0; pid < frame.Players(); pid++ {
+
+			frame.SetPid(pid)
+			my_ships := frame.MyShips()
+
+			// Both run on the same frame, so they share its cached maps...
+
+			serial_targets := make([]hal.Point, len(my_ships))
+			serial_scores := make([]float32, len(my_ships))
+
+			book := hal.Make2dBoolArray(frame.Width(), frame.Height())
+
+			for i, ship := range my_ships {
+				NewTurn(ship)
+				SetTarget(ship, book, nil)
+				serial_targets[i] = ship.Target()
+				serial_scores[i] = ship.Score
+			}
+
+			for _, ship := range my_ships {
+				NewTurn(ship)
+			}
+
+			book = hal.Make2dBoolArray(frame.Width(), frame.Height())
+			SetTargets(frame, my_ships, book, nil)
+
+			for i, ship := range my_ships {
+				if ship.Target() != serial_targets[i] || ship.Score != serial_scores[i] {
+					t.Errorf("turn %d, pid %d, ship %d: serial %v (%v), concurrent %v (%v)",
+						frame.Turn(), pid, ship.Sid, serial_targets[i], serial_scores[i], ship.Target(), ship.Score)
+				}
+			}
+
+			checked += len(my_ships)
+		}
+	}
+
+	if checked == 0 {
+		t.Fatalf("no ships checked")
+	}
+}
+
+func try_parse(frame *hal.Frame) (ok bool) {
+
+	// Parse() panics at the end of input. Any other panic is a real failure.
+
+	defer func() {
+		if p := recover(); p != nil {
+			if p != "End of input." {
+				panic(p)
+			}
+			ok = false
+		}
+	}()
+
+	frame.Parse()
+	return true
+}
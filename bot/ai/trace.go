@@ -0,0 +1,187 @@
+package ai
+
+// Optional per-ship decision trace. Once StartTrace() is called, every Step()
+// writes one JSON line per ship: its state, target and desires, and the list
+// of events that set (or cleared) its command, in order, so the last event
+// says which phase chose the final command and why. Read back with ReadTrace(),
+// or with tools/trace.
+//
+// When tracing is off the tracer is nil, and its methods do nothing.
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"os"
+
+	hal "../core"
+)
+
+type TraceEvent struct {
+	Phase			string		`json:"phase"`
+	Reason			string		`json:"reason"`
+	Command			string		`json:"command"`		// The ship's command after the event
+}
+
+type TraceRecord struct {
+	Turn			int				`json:"turn"`
+	Sid				int				`json:"sid"`
+	X				int				`json:"x"`
+	Y				int				`json:"y"`
+	Halite			int				`json:"halite"`
+	Returning		bool			`json:"returning"`
+	FinalDash		bool			`json:"final_dash"`
+	Target			*hal.Point		`json:"target,omitempty"`
+	Score			float32			`json:"score"`
+	BuildSite		*hal.Point		`json:"build_site,omitempty"`
+	Route			[]hal.RouteStep	`json:"route,omitempty"`
+	Desires			[]string		`json:"desires"`
+	Command			string			`json:"command"`
+	Events			[]TraceEvent	`json:"events"`
+}
+
+func (self *TraceRecord) String() string {
+
+	target := "none"
+	if self.Target != nil {
+		target = fmt.Sprintf("%d %d (score %.1f)", self.Target.X, self.Target.Y, self.Score)
+	}
+
+	s := fmt.Sprintf("t %3d: ship %d at %d %d carrying %d, returning %v, final dash %v, target %s, desires %v, command %q",
+		self.Turn, self.Sid, self.X, self.Y, self.Halite, self.Returning, self.FinalDash, target, self.Desires, self.Command)
+
+	for _, e := range self.Events {
+		s += fmt.Sprintf("\n        %-12s %-4q %s", e.Phase, e.Command, e.Reason)
+	}
+
+	return s
+}
+
+// ------------------------------------------------------------
+
+type Tracer struct {
+	outfile			*os.File
+	outfilename		string
+	closed			bool
+	events			map[int][]TraceEvent		// sid --> events this turn
+}
+
+var tracer *Tracer
+
+func StartTrace(outfilename string) {
+	tracer.Close()
+	tracer = &Tracer{outfilename: outfilename, events: make(map[int][]TraceEvent)}
+}
+
+func StopTrace() {
+	tracer.Close()
+}
+
+func (self *Tracer) Begin() {
+	if self == nil {
+		return
+	}
+	self.events = make(map[int][]TraceEvent)
+}
+
+func (self *Tracer) Note(ship *hal.Ship, phase, format_string string, args ...interface{}) {
+	if self == nil || self.closed {
+		return
+	}
+	self.events[ship.Sid] = append(self.events[ship.Sid], TraceEvent{phase, fmt.Sprintf(format_string, args...), ship.Command})
+}
+
+func (self *Tracer) Write(frame *hal.Frame, my_ships []*hal.Ship) {
+
+	if self == nil || self.closed {
+		return
+	}
+
+	if self.outfile == nil {
+		var err error
+		self.outfile, err = os.Create(self.outfilename)
+		if err != nil {
+			self.closed = true
+			return
+		}
+	}
+
+	w := bufio.NewWriter(self.outfile)
+
+	for _, ship := range my_ships {
+
+		r := TraceRecord{
+			Turn: frame.Turn(),
+			Sid: ship.Sid,
+			X: ship.X,
+			Y: ship.Y,
+			Halite: ship.Halite,
+			Returning: ship.Returning,
+			FinalDash: ship.FinalDash,
+			Score: ship.Score,
+			Route: ship.Route(),
+			Desires: ship.Desires,
+			Command: ship.Command,
+			Events: self.events[ship.Sid],
+		}
+
+		if ship.TargetOK() {
+			target := ship.Target()
+			r.Target = &target
+		}
+
+		if ship.HasBuildSite() {
+			site := ship.BuildSite()
+			r.BuildSite = &site
+		}
+
+		s, _ := json.Marshal(r)
+		w.Write(append(s, '\n'))
+	}
+
+	w.Flush()
+}
+
+func (self *Tracer) Close() {
+	if self == nil || self.closed {
+		return
+	}
+	self.closed = true
+	if self.outfile != nil {
+		self.outfile.Close()
+	}
+}
+
+// ------------------------------------------------------------
+
+func ReadTrace(filename string, sid int) ([]*TraceRecord, error) {
+
+	// All records for ship <sid>, in turn order. A truncated last line (e.g. the
+	// bot was killed mid-write) is ignored.
+
+	infile, err := os.Open(filename)
+	if err != nil {
+		return nil, err
+	}
+	defer infile.Close()
+
+	var ret []*TraceRecord
+
+	scanner := bufio.NewScanner(infile)
+	scanner.Buffer(make([]byte, 64 * 1024), 16 * 1024 * 1024)
+
+	for scanner.Scan() {
+
+		r := new(TraceRecord)
+
+		if json.Unmarshal(scanner.Bytes(), r) != nil {
+			continue
+		}
+
+		if r.Sid == sid {
+			ret = append(ret, r)
+		}
+	}
+
+	return ret, scanner.Err()
+}
@@ -2,34 +2,97 @@ package config
 
 import (
 	"flag"
+	"time"
 )
 
+var Attack bool
 var Crash bool
+var LogJSON bool
+var MapTest bool
 var NoAntiEnemyCollision bool
 var NoAntiSelfCollision bool
+var NoInspire bool
+var NoLookahead bool
+var NoRoutes bool
+var OldSpawn bool
 var RemakeTest bool
+var SerialTargets bool
 var SimTest bool
+var Timings bool
+var Trace bool
+
+var LogLevel string
+var PprofTurns string
+var Silence string
+var SimReplay string
+var SnapshotTurns string
+var Stats string
+
+var margin_arg int
+var TimeMargin time.Duration = 500 * time.Millisecond
 
 var gen_min_arg float64
 var GenMin float64 = 0.5
 
 func ParseCommandLine() {
 
+	flag.BoolVar(&Attack, "attack", false, "ram laden enemies in 2 player games")
 	flag.BoolVar(&Crash, "crash", false, "randomly crash")
+	flag.BoolVar(&LogJSON, "logjson", false, "also write the log as JSON lines")
+	flag.BoolVar(&MapTest, "maptest", false, "test the incremental map updates")
 	flag.BoolVar(&NoAntiEnemyCollision, "noantienemycollision", false, "disable anti-enemy-collision")
 	flag.BoolVar(&NoAntiSelfCollision, "noantiselfcollision", false, "disable recursive anti-self-collision")
+	flag.BoolVar(&NoInspire, "noinspire", false, "don't seek inspiration in games with more than 2 players")
+	flag.BoolVar(&NoLookahead, "nolookahead", false, "disable the lookahead search for contested ships")
+	flag.BoolVar(&NoRoutes, "noroutes", false, "mine single target cells instead of planned routes")
+	flag.BoolVar(&OldSpawn, "oldspawn", false, "spawn while ground halite is above GenMin, instead of using the spawn model")
 	flag.BoolVar(&RemakeTest, "remaketest", false, "test the frame remaker")
+	flag.BoolVar(&SerialTargets, "serialtargets", false, "find targets one ship at a time")
 	flag.BoolVar(&SimTest, "simtest", false, "test the simulator")
+	flag.BoolVar(&Timings, "timings", false, "time each phase of the turn and log percentiles at game end")
+	flag.BoolVar(&Trace, "trace", false, "write a per-ship decision trace")
+
+	flag.StringVar(&LogLevel, "loglevel", "info", "minimum log level: debug, info, warn or error")
+	flag.StringVar(&PprofTurns, "pprof", "", "comma-separated turns to write CPU profiles for, e.g. 100,300")
+	flag.StringVar(&Silence, "silence", "", "comma-separated subsystems to leave out of the log, e.g. resolver,safety")
+	flag.StringVar(&SimReplay, "simreplay", "", "play the game out in the simulator first, saving it as a replay to this file (.hlt or .zst are compressed, needing zstd on PATH)")
+	flag.StringVar(&SnapshotTurns, "snapshot", "", "comma-separated turns to save frame snapshots for, e.g. 100,300")
+	flag.StringVar(&Stats, "stats", "", "write per-turn statistics for all players: csv or jsonl")
 
-	flag.Float64Var(&gen_min_arg, "genmin", -1, "halite required to generate a ship")
+	flag.IntVar(&margin_arg, "margin", -1, "milliseconds of each turn to leave spare")
+
+	flag.Float64Var(&gen_min_arg, "genmin", -1, "halite required to generate a ship (implies -oldspawn)")
 
 	flag.Parse()
 }
 
+func SetTimeMargin(size int) {
+
+	// Bigger maps have more ships and more variable turn times.
+
+	if margin_arg >= 0 {
+		TimeMargin = time.Duration(margin_arg) * time.Millisecond
+		return
+	}
+
+	if size <= 32 {
+		TimeMargin = 300 * time.Millisecond
+	} else if size <= 40 {
+		TimeMargin = 350 * time.Millisecond
+	} else if size <= 48 {
+		TimeMargin = 400 * time.Millisecond
+	} else if size <= 56 {
+		TimeMargin = 450 * time.Millisecond
+	} else {
+		TimeMargin = 500 * time.Millisecond
+	}
+}
+
 func SetGenMin(size int, players int) {
 
 	if gen_min_arg >= 0 {
 		GenMin = gen_min_arg
+		OldSpawn = true
 		return
 	}
 
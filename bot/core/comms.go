@@ -4,6 +4,7 @@ import (
 	"bufio"
 	"encoding/json"
 	"fmt"
+	"io"
 	"os"
 	"sort"
 	"strconv"
@@ -21,12 +22,24 @@ type TokenParser struct {
 }
 
 func NewTokenParser() *TokenParser {
+	return NewTokenParserFrom(os.Stdin)
+}
+
+func NewTokenParserFrom(r io.Reader) *TokenParser {
 	ret := new(TokenParser)
-	ret.scanner = bufio.NewScanner(os.Stdin)
+	ret.scanner = bufio.NewScanner(r)
 	ret.scanner.Split(bufio.ScanWords)
 	return ret
 }
 
+func SetInput(r io.Reader) {
+
+	// For tools that read a saved game (e.g. from misc_scripts/inputsaver.go)
+	// instead of stdin. Must come before PrePreParse().
+
+	token_parser = NewTokenParserFrom(r)
+}
+
 func (self *TokenParser) Int() int {
 	bl := self.scanner.Scan()
 	if bl == false {
@@ -144,6 +157,7 @@ func (self *Frame) Parse() {
 
 	self.turn = token_parser.Int() - 1			// Out by 1 correction
 	self.ParseTime = time.Now()						// Must come after the first read
+	self.simulated = false
 
 	// Note: we create brand new objects for literally everything;
 	// No holding onto the old ones.
@@ -154,6 +168,8 @@ func (self *Frame) Parse() {
 	old_halite := self.halite
 	old_ship_id_lookup := self.ship_id_lookup
 
+	old_frame := *self				// Shallow copy, for updating the cached maps at the end
+
 	// Clear all the data...
 
 	self.Zerofy()
@@ -195,6 +211,7 @@ func (self *Frame) Parse() {
 			ship.Inspired = false					// Will set this correctly later
 			ship.Owner = pid
 			ship.Command = ""
+			ship.ClearDodged()
 
 			self.ships = append(self.ships, ship)
 			self.ship_xy_lookup[Point{ship.X, ship.Y}] = ship
@@ -230,10 +247,17 @@ func (self *Frame) Parse() {
 
 	cell_update_count := token_parser.Int()
 
+	var changed []Point
+	seen := make(map[Point]bool)
+
 	for n := 0; n < cell_update_count; n++ {
 		x := token_parser.Int()
 		y := token_parser.Int()
 		self.halite[x][y] = token_parser.Int()
+		if seen[Point{x, y}] == false {
+			changed = append(changed, Point{x, y})
+			seen[Point{x, y}] = true
+		}
 	}
 
 	// ------------------------------------------------
@@ -245,6 +269,20 @@ func (self *Frame) Parse() {
 
 	self.FixInspiration()
 
+	if self.predictor != nil {
+		self.predictor.Observe(&old_frame, self)
+	}
+
+	if self.profiler != nil {
+		self.profiler.Observe(&old_frame, self)
+	}
+
+	self.inherit_maps(&old_frame, changed)
+
+	if self.stats != nil {
+		self.stats.Observe(&old_frame, self)
+	}
+
 	// self.Log("Parsing took %v", time.Now().Sub(self.ParseTime))
 
 	return
@@ -269,7 +307,7 @@ func (self *Frame) Send() {
 			commands = append(commands, "g")
 			budget_left -= self.Constants.NEW_ENTITY_ENERGY_COST
 		} else {
-			self.Log("Warning: GENERATE command blocked due to lack of resources!")
+			self.Logger("comms").Warn("GENERATE command blocked due to lack of resources!")
 		}
 	}
 
@@ -285,7 +323,7 @@ func (self *Frame) Send() {
 					commands = append(commands, fmt.Sprintf("c %d", ship.Sid))
 					budget_left -= required
 				} else {
-					self.Log("Warning: CONSTRUCT command blocked due to lack of resources!")
+					self.Logger("comms").Warn("CONSTRUCT command blocked due to lack of resources!", "sid", ship.Sid)
 				}
 			} else {
 				commands = append(commands, fmt.Sprintf("m %d %s", ship.Sid, ship.Command))
@@ -294,7 +332,7 @@ func (self *Frame) Send() {
 	}
 
 	output := strings.Join(commands, " ")
-	fmt.Printf(output)
+	fmt.Print(output)
 	fmt.Printf("\n")
 	return
 }
@@ -44,6 +44,6 @@ func (self *Frame) LogConstants() {
 	if err != nil {
 		self.LogWithoutTurn("%v", err)
 	} else {
-		self.LogWithoutTurn(string(s))
+		self.LogWithoutTurn("%s", string(s))
 	}
 }
@@ -0,0 +1,48 @@
+package core
+
+import (
+	"time"
+)
+
+const (
+	TURN_TIME = 2 * time.Second			// Engine's default time limit per turn
+)
+
+// A Deadline is the point after which the AI should stop thinking and go with
+// what it has. A nil Deadline never expires, which is what simulations use.
+
+type Deadline struct {
+	start			time.Time
+	end				time.Time
+}
+
+func (self *Frame) NewDeadline(margin time.Duration) *Deadline {
+
+	// Measured from when the turn's input arrived, leaving <margin> spare.
+
+	return &Deadline{
+		start:	self.ParseTime,
+		end:	self.ParseTime.Add(TURN_TIME - margin),
+	}
+}
+
+func (self *Deadline) Expired() bool {
+	if self == nil {
+		return false
+	}
+	return time.Now().After(self.end)
+}
+
+func (self *Deadline) Remaining() time.Duration {
+	if self == nil {
+		return TURN_TIME
+	}
+	return self.end.Sub(time.Now())
+}
+
+func (self *Deadline) Elapsed() time.Duration {
+	if self == nil {
+		return 0
+	}
+	return time.Now().Sub(self.start)
+}
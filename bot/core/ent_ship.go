@@ -27,6 +27,19 @@ type Ship struct {
 	Desires						[]string	// Might get polluted with sims etc but OK as long as we clear it each turn.
 	Returning					bool
 	FinalDash					bool
+
+	build_site					Point		// Where this ship has been sent to build a dropoff, if anywhere
+	build_site_ok				bool
+
+	route						[]RouteStep	// Planned mining route, if any. Shared between copies, so never modified in place.
+
+	dodged						Point		// Where this ship would have gone this turn, if it changed course for safety
+	dodged_ok					bool		// (cleared each parse; the Profiler checks next turn whether it was right)
+}
+
+type RouteStep struct {
+	Point
+	Turns						int			// Turns still to spend mining here
 }
 
 // For the AI, ships either have a target or not. Attempting to read
@@ -53,6 +66,74 @@ func (self *Ship) ClearTarget() {
 	self.target_ok = false
 }
 
+// Likewise, a ship may have been designated to build a dropoff somewhere.
+
+func (self *Ship) BuildSite() Point {
+	if self.build_site_ok == false {
+		panic("Bad build site")
+	}
+	return self.build_site
+}
+
+func (self *Ship) HasBuildSite() bool {
+	return self.build_site_ok
+}
+
+func (self *Ship) SetBuildSite(pos XYer) {
+	self.build_site = Point{pos.GetX(), pos.GetY()}
+	self.build_site_ok = true
+}
+
+func (self *Ship) ClearBuildSite() {
+	self.build_site = Point{0, 0}
+	self.build_site_ok = false
+}
+
+func (self *Ship) SetDodged(pos XYer) {
+	self.dodged = Point{Mod(pos.GetX(), self.Frame.width), Mod(pos.GetY(), self.Frame.height)}
+	self.dodged_ok = true
+}
+
+func (self *Ship) ClearDodged() {
+	self.dodged = Point{0, 0}
+	self.dodged_ok = false
+}
+
+// And a ship may have a mining route: a series of cells to mine, in order.
+// The route slice is shared by every copy of the ship (sims, remakes, the next
+// turn's frame), so all changes go through these methods, which never write
+// to the existing slice.
+
+func (self *Ship) Route() []RouteStep {		// Callers must not modify the result
+	return self.route
+}
+
+func (self *Ship) HasRoute() bool {
+	return len(self.route) > 0
+}
+
+func (self *Ship) SetRoute(route []RouteStep) {
+	self.route = append([]RouteStep(nil), route...)
+}
+
+func (self *Ship) ClearRoute() {
+	self.route = nil
+}
+
+func (self *Ship) PopRoute() {
+	if len(self.route) > 0 {
+		self.route = self.route[1:]
+	}
+}
+
+func (self *Ship) SpendRouteTurn() {		// Count one turn of mining at the first step
+	if len(self.route) > 0 {
+		route := append([]RouteStep(nil), self.route...)
+		route[0].Turns--
+		self.route = route
+	}
+}
+
 // ---------------------------------------
 
 func (self *Ship) String() string {
@@ -86,6 +167,36 @@ func (self *Ship) MoveCost() int {
 	}
 }
 
+func (self *Ship) MoveOdds() map[string]float64 {
+
+	// How likely this ship is to make each move next turn.
+	// Mostly meant for enemy ships, whose intentions we can only guess.
+
+	if self.Frame.predictor != nil {
+		return self.Frame.predictor.Odds(self)
+	}
+	return self.CrudeMoveOdds()
+}
+
+func (self *Ship) CrudeMoveOdds() map[string]float64 {
+
+	// A crude guess, for when there's no predictor.
+
+	if self.Halite < self.MoveCost() {
+		return map[string]float64{"o": 1.0}
+	}
+
+	stay := 0.2
+
+	if self.Halite < self.Frame.Constants.MAX_ENERGY * 4 / 5 && self.HaliteAt() > self.Frame.AverageGroundHalite() {
+		stay = 0.6
+	}
+
+	each := (1 - stay) / 4
+
+	return map[string]float64{"o": stay, "e": each, "w": each, "s": each, "n": each}
+}
+
 func (self *Ship) NearestDropoff() *Dropoff {
 
 	possibles := self.Frame.Dropoffs(self.Owner)
@@ -22,6 +22,12 @@ type Frame struct {
 
 	turn						int
 	highest_sid_seen			int							// Mostly for the simulator, which needs to generate unique new sids
+	predictor					*MovePredictor				// Lasts the whole game; shared with simulated frames
+	profiler					*Profiler					// Likewise
+	stats						*StatsKeeper				// Likewise
+
+	simulated					bool						// Made by Remake() or SimGen(), not Parse(); keeps their timings apart
+	throwaway					bool						// Made by RemakeThrowaway() or SimGen() of such; never inherits maps
 
 	// All of the following are regenerated from scratch each turn...
 
@@ -33,14 +39,18 @@ type Frame struct {
 	ship_id_lookup				map[int]*Ship
 	generate					map[int]bool				// Whether the AI wants to send a "g" command
 
-	// The following are cleared each parse / simgen / remake, then made when asked for, and cached...
+	// The following are cleared each parse / simgen / remake, then made when asked for, and cached.
+	// If the previous frame had made them, they are instead inherited, with updates (see map_update.go)...
 
 	wealth_map					*WealthMap
+	wealth_table				*WealthTable				// Never inherited, but cheap to make
 	inspiration_map				map[int]*InspirationMap
+	inspiration_forecast		map[int]*InspirationForecast
 	dropoff_dist_map			map[int]*DropoffDistMap
 	friendly_dist_map			map[int]*FriendlyDistMap
 	enemy_dist_map				map[int]*EnemyDistMap
 	contest_map					map[int]*ContestMap
+	enemy_risk_map				map[int]*EnemyRiskMap
 
 	ground_halite				int
 }
@@ -50,6 +60,9 @@ func NewGame() *Frame {
 	frame := new(Frame)
 	frame.turn = -1
 	frame.highest_sid_seen = -1
+	frame.predictor = NewMovePredictor()
+	frame.profiler = NewProfiler()
+	frame.stats = NewStatsKeeper()
 
 	return frame
 }
@@ -70,20 +83,38 @@ func (self *Frame) Zerofy() {
 	// Cached stuff that gets remade when asked for...
 
 	self.wealth_map = nil
+	self.wealth_table = nil
 	self.inspiration_map = nil
+	self.inspiration_forecast = nil
 	self.dropoff_dist_map = nil
 	self.friendly_dist_map = nil
 	self.enemy_dist_map = nil
 	self.contest_map = nil
+	self.enemy_risk_map = nil
 	self.ground_halite = 0
 }
 
 func (self *Frame) Remake() *Frame {			// This is a deep copy
+	return self.remake(false)
+}
+
+func (self *Frame) RemakeThrowaway() *Frame {
+
+	// Like Remake(), but neither the copy nor anything SimGen() makes from it
+	// inherits the maps; they're built from scratch if ever asked for. Worth it
+	// for lookahead frames, which mostly never look at a map.
+
+	return self.remake(true)
+}
+
+func (self *Frame) remake(throwaway bool) *Frame {
 
 	g := new(Frame)
 	*g = *self			// Everything not explicitly changed will be the same
 
 	g.ParseTime = time.Now()
+	g.simulated = true
+	g.throwaway = throwaway
 
 	g.Zerofy()			// Clear all the data!
 
@@ -118,6 +149,10 @@ func (self *Frame) Remake() *Frame {			// This is a deep copy
 		g.generate[key] = val
 	}
 
+	if g.throwaway == false {
+		g.inherit_maps(self, nil)
+	}
+
 	return g
 }
 
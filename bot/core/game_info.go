@@ -156,21 +156,46 @@ func (self *Frame) ShipCanDropoffAt(ship *Ship, pos XYer) bool {
 
 func (self *Frame) WealthMap() *WealthMap {		// Return cached value if available.
 	if self.wealth_map == nil {
+		done := self.time_map("wealth")
 		self.wealth_map = NewWealthMap(self)
+		done()
 	}
 	return self.wealth_map
 }
 
+func (self *Frame) WealthTable() *WealthTable {	// Return cached value if available.
+	if self.wealth_table == nil {
+		done := self.time_map("wealth table")
+		self.wealth_table = NewWealthTable(self)
+		done()
+	}
+	return self.wealth_table
+}
+
 func (self *Frame) InspirationMap() *InspirationMap {
 	if self.inspiration_map == nil {
 		self.inspiration_map = make(map[int]*InspirationMap)
 	}
 	if self.inspiration_map[self.pid] == nil {
+		done := self.time_map("inspiration")
 		self.inspiration_map[self.pid] = NewInspirationMap(self)
+		done()
 	}
 	return self.inspiration_map[self.pid]
 }
 
+func (self *Frame) InspirationForecast() *InspirationForecast {
+	if self.inspiration_forecast == nil {
+		self.inspiration_forecast = make(map[int]*InspirationForecast)
+	}
+	if self.inspiration_forecast[self.pid] == nil {
+		done := self.time_map("forecast")
+		self.inspiration_forecast[self.pid] = NewInspirationForecast(self, INSPIRATION_FORECAST_TURNS)
+		done()
+	}
+	return self.inspiration_forecast[self.pid]
+}
+
 func (self *Frame) InspirationCheck(pos XYer) bool {
 	return self.InspirationMap().Check(pos)
 }
@@ -180,7 +205,9 @@ func (self *Frame) DropoffDistMap() *DropoffDistMap {
 		self.dropoff_dist_map = make(map[int]*DropoffDistMap)
 	}
 	if self.dropoff_dist_map[self.pid] == nil {
+		done := self.time_map("dropoff dist")
 		self.dropoff_dist_map[self.pid] = NewDropoffDistMap(self)
+		done()
 	}
 	return self.dropoff_dist_map[self.pid]
 }
@@ -190,7 +217,9 @@ func (self *Frame) FriendlyDistMap() *FriendlyDistMap {
 		self.friendly_dist_map = make(map[int]*FriendlyDistMap)
 	}
 	if self.friendly_dist_map[self.pid] == nil {
+		done := self.time_map("friendly dist")
 		self.friendly_dist_map[self.pid] = NewFriendlyDistMap(self)
+		done()
 	}
 	return self.friendly_dist_map[self.pid]
 }
@@ -200,7 +229,9 @@ func (self *Frame) EnemyDistMap() *EnemyDistMap {
 		self.enemy_dist_map = make(map[int]*EnemyDistMap)
 	}
 	if self.enemy_dist_map[self.pid] == nil {
+		done := self.time_map("enemy dist")
 		self.enemy_dist_map[self.pid] = NewEnemyDistMap(self)
+		done()
 	}
 	return self.enemy_dist_map[self.pid]
 }
@@ -210,11 +241,30 @@ func (self *Frame) ContestMap() *ContestMap {
 		self.contest_map = make(map[int]*ContestMap)
 	}
 	if self.contest_map[self.pid] == nil {
-		self.contest_map[self.pid] = NewContestMap(self.FriendlyDistMap(), self.EnemyDistMap())
+		friendly, enemy := self.FriendlyDistMap(), self.EnemyDistMap()		// Not counted in the contest map's time
+		done := self.time_map("contest")
+		self.contest_map[self.pid] = NewContestMap(friendly, enemy)
+		done()
 	}
 	return self.contest_map[self.pid]
 }
 
+func (self *Frame) EnemyRiskMap() *EnemyRiskMap {
+	if self.enemy_risk_map == nil {
+		self.enemy_risk_map = make(map[int]*EnemyRiskMap)
+	}
+	if self.enemy_risk_map[self.pid] == nil {
+		done := self.time_map("enemy risk")
+		self.enemy_risk_map[self.pid] = NewEnemyRiskMap(self)
+		done()
+	}
+	return self.enemy_risk_map[self.pid]
+}
+
+func (self *Frame) Predictor() *MovePredictor {		// Maybe nil
+	return self.predictor
+}
+
 func (self *Frame) InitialGroundHalite() int {
 	return self.initial_ground_halite
 }
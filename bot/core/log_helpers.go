@@ -5,19 +5,28 @@ import (
 	"../logging"
 )
 
+// Frame.Log() and friends make plain INFO records with just the turn and pid.
+// For anything else, get a logging.Context from Logger() and use its methods,
+// e.g. frame.Logger("resolver").Ship(sid).Debug("cancelling move", "move", "e")
 
+func (self *Frame) Logger(subsystem string) logging.Context {
+	return logging.Context{Turn: self.Turn(), Pid: self.pid, Sid: -1, Subsystem: subsystem}
+}
+
+func (self *Ship) Logger(subsystem string) logging.Context {
+	return self.Frame.Logger(subsystem).Ship(self.Sid)
+}
 
 func (self *Frame) Log(format_string string, args ...interface{}) {
-	format_string = fmt.Sprintf("t %3d: ", self.Turn()) + format_string
-	logging.Log(format_string, args...)
+	self.Logger("").Info(fmt.Sprintf(format_string, args...))
 }
 
 func (self *Frame) LogOnce(format_string string, args ...interface{}) bool {
-	format_string = "t %3d: " + format_string
-	var newargs []interface{}
-	newargs = append(newargs, self.Turn())
-	newargs = append(newargs, args...)
-	return logging.LogOnce(format_string, newargs...)
+	if logging.Enabled(logging.INFO, "") == false || logging.Once(format_string) == false {		// Note that it's format_string that is checked / saved
+		return false
+	}
+	self.Log(format_string, args...)
+	return true
 }
 
 func (self *Frame) LogWithoutTurn(format_string string, args ...interface{}) {
@@ -26,6 +35,30 @@ func (self *Frame) LogWithoutTurn(format_string string, args ...interface{}) {
 
 
 
+func init() {
+	logging.DefineFlogLayer("wealth", "WealthMap", 0, 16000, "#000000", "#ffd700")
+	logging.DefineFlogLayer("contest", "ContestMap (negative is ours)", -8, 8, "#3060ff", "#ff3030")
+	logging.DefineFlogLayer("dropoff_dist", "DropoffDistMap", 0, 32, "#80ff80", "#000000")
+	logging.DefineFlogLayer("enemy_dist", "EnemyDistMap", 0, 16, "#ff3030", "#000000")
+	logging.DefineFlogLayer("friendly_dist", "FriendlyDistMap", 0, 16, "#3060ff", "#000000")
+	logging.DefineFlogLayer("inspiration", "InspirationMap enemy counts", 0, 4, "#202020", "#40e0d0")
+	for turns := 1; turns <= INSPIRATION_FORECAST_TURNS; turns++ {
+		logging.DefineFlogLayer(fmt.Sprintf("inspiration_%d", turns), fmt.Sprintf("InspirationForecast at t+%d", turns), 0, 1, "#202020", "#40e0d0")
+	}
+}
+
 func (self *Frame) Flog(x, y int, msg, colour string) {
 	logging.Flog(self.Turn(), x, y, msg, colour)
 }
+
+func (self *Frame) FlogIn(x, y int, layer, msg, colour string) {
+	logging.FlogIn(self.Turn(), x, y, layer, msg, colour)
+}
+
+func (self *Frame) FlogValue(x, y int, layer string, value float64) {
+	logging.FlogValue(self.Turn(), x, y, layer, value)
+}
+
+func (self *Frame) FlogArrow(from, to XYer, layer, colour string) {
+	logging.FlogArrow(self.Turn(), from.GetX(), from.GetY(), to.GetX(), to.GetY(), layer, colour)
+}
@@ -1,7 +1,6 @@
 package core
 
 import (
-	"fmt"
 	"../logging"
 )
 
@@ -29,8 +28,7 @@ func NewContestMap(a *FriendlyDistMap, b *EnemyDistMap) *ContestMap {
 func (self *ContestMap) Flog(turn int) {
 	for x := 0; x < len(self.Values); x++ {
 		for y := 0; y < len(self.Values[0]); y++ {
-			s := fmt.Sprintf("Contest: %v", self.Values[x][y])
-			logging.Flog(turn, x, y, s, "")
+			logging.FlogValue(turn, x, y, "contest", float64(self.Values[x][y]))
 		}
 	}
 }
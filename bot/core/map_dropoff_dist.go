@@ -1,7 +1,6 @@
 package core
 
 import (
-	"fmt"
 	"../logging"
 )
 
@@ -59,8 +58,7 @@ func NewDropoffDistMap(frame *Frame) *DropoffDistMap {
 func (self *DropoffDistMap) Flog(turn int) {
 	for x := 0; x < len(self.Values); x++ {
 		for y := 0; y < len(self.Values[0]); y++ {
-			s := fmt.Sprintf("Dropoff dist: %v", self.Values[x][y])
-			logging.Flog(turn, x, y, s, "")
+			logging.FlogValue(turn, x, y, "dropoff_dist", float64(self.Values[x][y]))
 		}
 	}
 }
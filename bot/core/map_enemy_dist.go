@@ -1,7 +1,6 @@
 package core
 
 import (
-	"fmt"
 	"../logging"
 )
 
@@ -64,8 +63,7 @@ func NewEnemyDistMap(frame *Frame) *EnemyDistMap {
 func (self *EnemyDistMap) Flog(turn int) {
 	for x := 0; x < len(self.Values); x++ {
 		for y := 0; y < len(self.Values[0]); y++ {
-			s := fmt.Sprintf("Enemy dist: %v", self.Values[x][y])
-			logging.Flog(turn, x, y, s, "")
+			logging.FlogValue(turn, x, y, "enemy_dist", float64(self.Values[x][y]))
 		}
 	}
 }
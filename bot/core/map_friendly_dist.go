@@ -1,7 +1,6 @@
 package core
 
 import (
-	"fmt"
 	"../logging"
 )
 
@@ -63,8 +62,7 @@ func NewFriendlyDistMap(frame *Frame) *FriendlyDistMap {
 func (self *FriendlyDistMap) Flog(turn int) {
 	for x := 0; x < len(self.Values); x++ {
 		for y := 0; y < len(self.Values[0]); y++ {
-			s := fmt.Sprintf("Friendly dist: %v", self.Values[x][y])
-			logging.Flog(turn, x, y, s, "")
+			logging.FlogValue(turn, x, y, "friendly_dist", float64(self.Values[x][y]))
 		}
 	}
 }
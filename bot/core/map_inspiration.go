@@ -50,8 +50,8 @@ func (self *InspirationMap) Check(pos XYer) bool {
 func (self *InspirationMap) Flog(turn int) {
 	for x := 0; x < len(self.Values); x++ {
 		for y := 0; y < len(self.Values[0]); y++ {
-			if self.Values[x][y] >= 2 {
-				logging.Flog(turn, x, y, "", "darkslategray")
+			if self.Values[x][y] > 0 {
+				logging.FlogValue(turn, x, y, "inspiration", float64(self.Values[x][y]))
 			}
 		}
 	}
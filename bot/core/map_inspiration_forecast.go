@@ -0,0 +1,163 @@
+package core
+
+import (
+	"fmt"
+	"sort"
+	"../logging"
+)
+
+const (
+	INSPIRATION_FORECAST_TURNS = 4
+)
+
+// The InspirationMap only knows where enemy ships are now. The forecast spreads
+// each enemy ship over the cells it might occupy in the next few turns (using
+// Ship.MoveOdds) and gives, per cell, the probability that enough enemies will
+// be within INSPIRATION_RADIUS for a ship there to be inspired.
+//
+// Probs[0] is the present (0 or 1), Probs[n] is n turns ahead.
+//
+// Sums are always taken in the same order (never in map order) so that the
+// same frame always gives exactly the same forecast.
+
+type InspirationForecast struct {
+	Probs			[][][]float64
+}
+
+func NewInspirationForecast(frame *Frame, turns int) *InspirationForecast {
+
+	width := frame.Width()
+	height := frame.Height()
+	radius := frame.Constants.INSPIRATION_RADIUS
+	threshold := frame.Constants.INSPIRATION_SHIP_COUNT
+
+	self := new(InspirationForecast)
+
+	// positions[n] is each enemy ship's position distribution after n turns...
+
+	enemies := frame.EnemyShips()
+
+	positions := make([]map[Point]float64, len(enemies))
+
+	for i, ship := range enemies {
+		positions[i] = map[Point]float64{Point{ship.X, ship.Y}: 1.0}
+	}
+
+	for n := 0; n <= turns; n++ {
+
+		if n > 0 {
+			for i, ship := range enemies {
+				positions[i] = step_distribution(frame, positions[i], ship.MoveOdds())
+			}
+		}
+
+		// For each cell, the distribution of how many enemies are within range,
+		// with everything at or above the threshold lumped together in counts[threshold].
+
+		counts := make([][][]float64, width)
+		for x := 0; x < width; x++ {
+			counts[x] = make([][]float64, height)
+			for y := 0; y < height; y++ {
+				counts[x][y] = make([]float64, threshold + 1)
+				counts[x][y][0] = 1.0
+			}
+		}
+
+		for i := range enemies {
+
+			// Probability that this ship is within range of each cell...
+
+			near := make(map[Point]float64)
+
+			for _, pos := range sorted_points(positions[i]) {
+				p := positions[i][pos]
+				for dy := -radius; dy <= radius; dy++ {
+					span := radius - Abs(dy)
+					for dx := -span; dx <= span; dx++ {
+						near[Point{Mod(pos.X + dx, width), Mod(pos.Y + dy, height)}] += p
+					}
+				}
+			}
+
+			for cell, q := range near {
+				if q > 1.0 { q = 1.0 }		// Floating point slop
+				c := counts[cell.X][cell.Y]
+				c[threshold] += c[threshold - 1] * q
+				for k := threshold - 1; k > 0; k-- {
+					c[k] = c[k] * (1 - q) + c[k - 1] * q
+				}
+				c[0] *= 1 - q
+			}
+		}
+
+		grid := Make2dFloatArray(width, height)
+		for x := 0; x < width; x++ {
+			for y := 0; y < height; y++ {
+				grid[x][y] = counts[x][y][threshold]
+			}
+		}
+
+		self.Probs = append(self.Probs, grid)
+	}
+
+	return self
+}
+
+func step_distribution(frame *Frame, dist map[Point]float64, odds map[string]float64) map[Point]float64 {
+
+	ret := make(map[Point]float64)
+
+	for _, pos := range sorted_points(dist) {
+		for _, move := range []string{"o", "e", "w", "s", "n"} {
+			q, ok := odds[move]
+			if ok == false {
+				continue
+			}
+			dx, dy := StringToDxDy(move)
+			ret[Point{Mod(pos.X + dx, frame.Width()), Mod(pos.Y + dy, frame.Height())}] += dist[pos] * q
+		}
+	}
+
+	return ret
+}
+
+func sorted_points(m map[Point]float64) []Point {
+
+	ret := make([]Point, 0, len(m))
+
+	for point := range m {
+		ret = append(ret, point)
+	}
+
+	sort.Slice(ret, func(a, b int) bool {
+		return ret[a].X < ret[b].X || (ret[a].X == ret[b].X && ret[a].Y < ret[b].Y)
+	})
+
+	return ret
+}
+
+func (self *InspirationForecast) Prob(pos XYer, turns int) float64 {
+
+	// Probability of the cell being inspired after <turns> turns;
+	// turns beyond the forecast use the furthest one available.
+
+	if turns < 0 {
+		turns = 0
+	}
+	if turns >= len(self.Probs) {
+		turns = len(self.Probs) - 1
+	}
+
+	return self.Probs[turns][pos.GetX()][pos.GetY()]
+}
+
+func (self *InspirationForecast) Flog(turn int, turns int) {
+	grid := self.Probs[turns]
+	for x := 0; x < len(grid); x++ {
+		for y := 0; y < len(grid[0]); y++ {
+			if grid[x][y] >= 0.05 {
+				logging.FlogValue(turn, x, y, fmt.Sprintf("inspiration_%d", turns), grid[x][y])
+			}
+		}
+	}
+}
@@ -0,0 +1,440 @@
+package core
+
+import (
+	"fmt"
+)
+
+// Rather than throwing the cached maps away every turn, a new frame can inherit
+// them from the frame it was made from, applying only what changed: the cells
+// whose halite changed and the ships / dropoffs that appeared, moved or died.
+//
+// Inherited maps are always copies, so that (e.g.) several SimGen() calls from
+// the same frame never share a map.
+
+func (self *Frame) inherit_maps(old *Frame, changed []Point) {
+
+	defer self.time_map("inherit")()
+
+	if old.wealth_map != nil {
+		self.wealth_map = old.wealth_map.updated(old, self, changed)
+	}
+
+	if old.ground_halite > 0 {
+		self.ground_halite = old.ground_halite
+		for _, point := range changed {
+			self.ground_halite += self.halite[point.X][point.Y] - old.halite[point.X][point.Y]
+		}
+	}
+
+	// The ships that appeared or moved, and the ships that died or moved...
+
+	var arrivals []*Ship
+	var departures []*Ship
+
+	for _, ship := range self.ships {
+		old_ship := old.ship_id_lookup[ship.Sid]
+		if old_ship == nil || old_ship.X != ship.X || old_ship.Y != ship.Y {
+			arrivals = append(arrivals, ship)
+		}
+	}
+
+	for _, old_ship := range old.ships {
+		ship := self.ship_id_lookup[old_ship.Sid]
+		if ship == nil || ship.X != old_ship.X || ship.Y != old_ship.Y {
+			departures = append(departures, old_ship)
+		}
+	}
+
+	for pid, imap := range old.inspiration_map {
+		if imap != nil {
+			if self.inspiration_map == nil {
+				self.inspiration_map = make(map[int]*InspirationMap)
+			}
+			self.inspiration_map[pid] = imap.updated(self, pid, arrivals, departures)
+		}
+	}
+
+	// The distance maps, from the sources they gained and lost...
+
+	unchanged_friendly := make(map[int]bool)
+	unchanged_enemy := make(map[int]bool)
+
+	for pid, dmap := range old.dropoff_dist_map {
+		if dmap != nil {
+			values, _ := update_dist_values(self, dmap.Values, old.dropoff_dist_sources(pid), self.dropoff_dist_sources(pid))
+			if self.dropoff_dist_map == nil {
+				self.dropoff_dist_map = make(map[int]*DropoffDistMap)
+			}
+			self.dropoff_dist_map[pid] = &DropoffDistMap{Values: values}
+		}
+	}
+
+	for pid, fmap := range old.friendly_dist_map {
+		if fmap != nil {
+			values, unchanged := update_dist_values(self, fmap.Values, old.friendly_dist_sources(pid), self.friendly_dist_sources(pid))
+			if self.friendly_dist_map == nil {
+				self.friendly_dist_map = make(map[int]*FriendlyDistMap)
+			}
+			self.friendly_dist_map[pid] = &FriendlyDistMap{Values: values}
+			unchanged_friendly[pid] = unchanged
+		}
+	}
+
+	for pid, emap := range old.enemy_dist_map {
+		if emap != nil {
+			values, unchanged := update_dist_values(self, emap.Values, old.enemy_dist_sources(pid), self.enemy_dist_sources(pid))
+			if self.enemy_dist_map == nil {
+				self.enemy_dist_map = make(map[int]*EnemyDistMap)
+			}
+			self.enemy_dist_map[pid] = &EnemyDistMap{Values: values}
+			unchanged_enemy[pid] = unchanged
+		}
+	}
+
+	// The contest map is just the difference of the other two, so is copied if neither
+	// changed and otherwise remade from them, which is cheap.
+
+	for pid, cmap := range old.contest_map {
+
+		if cmap == nil || self.friendly_dist_map[pid] == nil || self.enemy_dist_map[pid] == nil {
+			continue
+		}
+
+		if self.contest_map == nil {
+			self.contest_map = make(map[int]*ContestMap)
+		}
+
+		if unchanged_friendly[pid] && unchanged_enemy[pid] {
+			self.contest_map[pid] = &ContestMap{Values: copy_2d_int_array(cmap.Values)}
+		} else {
+			self.contest_map[pid] = NewContestMap(self.friendly_dist_map[pid], self.enemy_dist_map[pid])
+		}
+	}
+}
+
+func (self *Frame) changed_cells(other *Frame) []Point {
+
+	// Cells whose halite differs between the two frames.
+
+	var ret []Point
+
+	for x := 0; x < self.width; x++ {
+		for y := 0; y < self.height; y++ {
+			if self.halite[x][y] != other.halite[x][y] {
+				ret = append(ret, Point{x, y})
+			}
+		}
+	}
+
+	return ret
+}
+
+// ------------------------------------------------------------
+
+func (self *WealthMap) updated(old_frame, new_frame *Frame, changed []Point) *WealthMap {
+
+	ret := new(WealthMap)
+	ret.Values = copy_2d_int_array(self.Values)
+
+	for _, point := range changed {
+		delta := new_frame.halite[point.X][point.Y] - old_frame.halite[point.X][point.Y]
+		if delta != 0 {
+			ret.propagate(point.X, point.Y, delta, WEALTH_MAP_RADIUS)
+		}
+	}
+
+	return ret
+}
+
+func (self *InspirationMap) updated(frame *Frame, pid int, arrivals, departures []*Ship) *InspirationMap {
+
+	ret := new(InspirationMap)
+	ret.Threshold = self.Threshold
+	ret.Values = copy_2d_int_array(self.Values)
+
+	for _, ship := range departures {
+		if ship.Owner != pid {
+			ret.mark(frame, ship.X, ship.Y, -1)
+		}
+	}
+
+	for _, ship := range arrivals {
+		if ship.Owner != pid {
+			ret.mark(frame, ship.X, ship.Y, 1)
+		}
+	}
+
+	return ret
+}
+
+func (self *InspirationMap) mark(frame *Frame, ox, oy, value int) {
+
+	width := frame.Width()
+	height := frame.Height()
+	radius := frame.Constants.INSPIRATION_RADIUS
+
+	for y := 0; y <= radius; y++ {
+
+		startx := y - radius
+		endx := radius - y
+
+		for x := startx; x <= endx; x++ {
+
+			loc_x := Mod(ox + x, width)
+			loc_y := Mod(oy + y, height)
+			self.Values[loc_x][loc_y] += value
+
+			if y != 0 {
+				loc_y = Mod(oy - y, height)
+				self.Values[loc_x][loc_y] += value
+			}
+		}
+	}
+}
+
+// ------------------------------------------------------------
+// The sources of each distance map, as used by its constructor, but for an explicit pid.
+
+func (self *Frame) dropoff_dist_sources(pid int) map[Point]bool {
+	ret := make(map[Point]bool)
+	for _, dropoff := range self.Dropoffs(pid) {
+		ret[Point{dropoff.X, dropoff.Y}] = true
+	}
+	return ret
+}
+
+func (self *Frame) friendly_dist_sources(pid int) map[Point]bool {
+	ret := make(map[Point]bool)
+	for _, ship := range self.Ships(pid) {
+		ret[Point{ship.X, ship.Y}] = true
+	}
+	factory := self.Factory(pid)
+	ret[Point{factory.X, factory.Y}] = true
+	return ret
+}
+
+func (self *Frame) enemy_dist_sources(pid int) map[Point]bool {
+	ret := make(map[Point]bool)
+	for _, ship := range self.ships {
+		if ship.Owner != pid {
+			ret[Point{ship.X, ship.Y}] = true
+		}
+	}
+	for n := 0; n < self.players; n++ {
+		if n != pid {
+			ret[Point{self.dropoffs[n].X, self.dropoffs[n].Y}] = true
+		}
+	}
+	return ret
+}
+
+func update_dist_values(frame *Frame, values [][]int, old_sources, new_sources map[Point]bool) ([][]int, bool) {
+
+	// Returns the new values, and whether nothing changed. Lost sources are handled
+	// first: every cell that was nearest to a lost source is cleared, then refilled
+	// from its neighbours. Gained sources then spread outwards as in the constructor.
+
+	ret := copy_2d_int_array(values)
+
+	var lost []Point
+	var gained []Point
+
+	for point := range old_sources {
+		if new_sources[point] == false {
+			lost = append(lost, point)
+		}
+	}
+
+	for point := range new_sources {
+		if old_sources[point] == false {
+			gained = append(gained, point)
+		}
+	}
+
+	if len(lost) == 0 && len(gained) == 0 {
+		return ret, true
+	}
+
+	if len(lost) > 0 {
+		forget_sources(frame, ret, lost)
+	}
+
+	hotpoints := gained
+
+	for _, point := range gained {
+		ret[point.X][point.Y] = 0
+	}
+
+	for len(hotpoints) > 0 {
+
+		var next_hotpoints []Point
+
+		for _, hotpoint := range hotpoints {
+
+			neighbours := frame.Neighbours(hotpoint.X, hotpoint.Y)
+
+			for _, box := range neighbours {
+
+				if ret[box.X][box.Y] > ret[hotpoint.X][hotpoint.Y] + 1 {
+
+					ret[box.X][box.Y] = ret[hotpoint.X][hotpoint.Y] + 1
+					next_hotpoints = append(next_hotpoints, Point{box.X, box.Y})
+				}
+			}
+		}
+
+		hotpoints = next_hotpoints
+	}
+
+	return ret, false
+}
+
+func forget_sources(frame *Frame, values [][]int, lost []Point) {
+
+	// A cell was nearest to a lost source iff its value equals its distance from it,
+	// and every such cell can be reached from the source through other such cells,
+	// so a flood fill from each lost source finds them all.
+
+	cleared := Make2dBoolArray(frame.width, frame.height)
+	visited := Make2dIntArray(frame.width, frame.height)		// Which flood fill last visited each cell (1-based)
+
+	var cleared_list []Point
+
+	for n, source := range lost {
+
+		stamp := n + 1
+
+		visited[source.X][source.Y] = stamp
+
+		if cleared[source.X][source.Y] == false {
+			cleared[source.X][source.Y] = true
+			cleared_list = append(cleared_list, source)
+		}
+
+		hotpoints := []Point{source}
+
+		for depth := 0; len(hotpoints) > 0; depth++ {
+
+			var next_hotpoints []Point
+
+			for _, hotpoint := range hotpoints {
+				for _, box := range frame.Neighbours(hotpoint.X, hotpoint.Y) {
+					if visited[box.X][box.Y] != stamp && values[box.X][box.Y] == depth + 1 {
+						visited[box.X][box.Y] = stamp
+						if cleared[box.X][box.Y] == false {
+							cleared[box.X][box.Y] = true
+							cleared_list = append(cleared_list, box)
+						}
+						next_hotpoints = append(next_hotpoints, box)
+					}
+				}
+			}
+
+			hotpoints = next_hotpoints
+		}
+	}
+
+	// Refill the cleared cells from the cells around them, nearest first. No distance
+	// on the torus exceeds width + height, so a bucket per distance will do.
+
+	buckets := make([][]Point, frame.width + frame.height + 1)
+
+	for _, point := range cleared_list {
+		values[point.X][point.Y] = 9999
+	}
+
+	for _, point := range cleared_list {
+		for _, box := range frame.Neighbours(point.X, point.Y) {
+			if cleared[box.X][box.Y] == false && values[box.X][box.Y] + 1 < values[point.X][point.Y] {
+				values[point.X][point.Y] = values[box.X][box.Y] + 1
+			}
+		}
+		if values[point.X][point.Y] < len(buckets) {
+			buckets[values[point.X][point.Y]] = append(buckets[values[point.X][point.Y]], point)
+		}
+	}
+
+	for dist := 0; dist < len(buckets) - 1; dist++ {
+		for n := 0; n < len(buckets[dist]); n++ {				// The bucket can grow as we go
+			point := buckets[dist][n]
+			if values[point.X][point.Y] != dist {
+				continue
+			}
+			for _, box := range frame.Neighbours(point.X, point.Y) {
+				if cleared[box.X][box.Y] && values[box.X][box.Y] > dist + 1 {
+					values[box.X][box.Y] = dist + 1
+					buckets[dist + 1] = append(buckets[dist + 1], box)
+				}
+			}
+		}
+	}
+}
+
+// ------------------------------------------------------------
+
+func (self *Frame) CheckMaps() int {
+
+	// Compare every cached map (for the current pid) against a full rebuild,
+	// logging any differences. Returns the number of maps that differed.
+	// Also has the effect of creating all the maps, so they get inherited next turn.
+
+	differences := self.map_differences()
+
+	for _, diff := range differences {
+		self.Logger("maps").Error("CheckMaps: map differs from rebuild", "diff", diff)
+	}
+
+	return len(differences)
+}
+
+func (self *Frame) map_differences() []string {
+
+	var ret []string
+
+	checks := []struct {
+		name		string
+		cached		[][]int
+		fresh		[][]int
+	}{
+		{"WealthMap",		self.WealthMap().Values,		NewWealthMap(self).Values},
+		{"InspirationMap",	self.InspirationMap().Values,	NewInspirationMap(self).Values},
+		{"DropoffDistMap",	self.DropoffDistMap().Values,	NewDropoffDistMap(self).Values},
+		{"FriendlyDistMap",	self.FriendlyDistMap().Values,	NewFriendlyDistMap(self).Values},
+		{"EnemyDistMap",	self.EnemyDistMap().Values,		NewEnemyDistMap(self).Values},
+		{"ContestMap",		self.ContestMap().Values,		NewContestMap(NewFriendlyDistMap(self), NewEnemyDistMap(self)).Values},
+	}
+
+	for _, check := range checks {
+		if diff := first_difference(check.cached, check.fresh); diff != "" {
+			ret = append(ret, check.name + " " + diff)
+		}
+	}
+
+	ground_halite := self.GroundHalite()
+	self.ground_halite = 0
+	if ground_halite != self.GroundHalite() {
+		ret = append(ret, fmt.Sprintf("GroundHalite %d vs %d", ground_halite, self.GroundHalite()))
+	}
+
+	return ret
+}
+
+func first_difference(a, b [][]int) string {
+	for x := 0; x < len(a); x++ {
+		for y := 0; y < len(a[0]); y++ {
+			if a[x][y] != b[x][y] {
+				return fmt.Sprintf("at %d %d, %d vs %d", x, y, a[x][y], b[x][y])
+			}
+		}
+	}
+	return ""
+}
+
+func copy_2d_int_array(arr [][]int) [][]int {
+	ret := make([][]int, len(arr))
+	for x := 0; x < len(arr); x++ {
+		ret[x] = make([]int, len(arr[x]))
+		copy(ret[x], arr[x])
+	}
+	return ret
+}
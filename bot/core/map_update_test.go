@@ -0,0 +1,120 @@
+package core
+
+import (
+	"os"
+	"testing"
+)
+
+// Plays back a recorded game (as misc_scripts/inputsaver.go would save it), calling
+// <each> after every Parse(). Returns the number of turns parsed.
+
+func play_back(t *testing.T, filename string, each func(frame *Frame)) int {
+
+	infile, err := os.Open(filename)
+	if err != nil {
+		t.Fatalf("%v", err)
+	}
+	defer infile.Close()
+
+	SetInput(infile)
+	defer SetInput(os.Stdin)
+
+	frame := NewGame()
+	frame.PrePreParse()
+	frame.PreParse()
+
+	turns := 0
+
+	for try_parse(frame) {
+		each(frame)
+		turns++
+	}
+
+	return turns
+}
+
+// The fixture testdata/game-32-4p.txt is the first 150 turns of a 4 player game.
+
+const GAME_FIXTURE_TURNS = 150
+
+func try_parse(frame *Frame) (ok bool) {
+
+	// Parse() panics at the end of input. Any other panic is a real failure.
+
+	defer func() {
+		if p := recover(); p != nil {
+			if p != "End of input." {
+				panic(p)
+			}
+			ok = false
+		}
+	}()
+
+	frame.Parse()
+	return true
+}
+
+func TestIncrementalMaps(t *testing.T) {
+
+	// Every map (for every player's point of view) and the ground halite must match
+	// a full rebuild every turn. Making them all each turn means every one of them
+	// is inherited by the next frame.
+
+	turns := play_back(t, "testdata/game-32-4p.txt", func(frame *Frame) {
+		for pid := 0; pid < frame.Players(); pid++ {
+			frame.SetPid(pid)
+			for _, diff := range frame.map_differences() {
+				t.Errorf("turn %d, pid %d: %s", frame.Turn(), pid, diff)
+			}
+		}
+	})
+
+	if turns != GAME_FIXTURE_TURNS {
+		t.Fatalf("parsed %d turns, expected %d", turns, GAME_FIXTURE_TURNS)
+	}
+}
+
+func TestDistUpdateMovedSources(t *testing.T) {
+
+	// A source that moves is both lost and gained; the update must match a rebuild
+	// without having to start again.
+
+	frame := &Frame{width: 16, height: 12}
+
+	old_sources := map[Point]bool{{2, 3}: true, {10, 7}: true, {15, 11}: true}
+	new_sources := map[Point]bool{{3, 3}: true, {10, 7}: true, {0, 0}: true}
+
+	updated, unchanged := update_dist_values(frame, dist_values(frame, old_sources), old_sources, new_sources)
+
+	if unchanged {
+		t.Errorf("update reported no change")
+	}
+
+	if diff := first_difference(updated, dist_values(frame, new_sources)); diff != "" {
+		t.Errorf("update differs from rebuild %s", diff)
+	}
+}
+
+func dist_values(frame *Frame, sources map[Point]bool) [][]int {
+
+	// Brute force torus distance to the nearest source.
+
+	ret := Make2dIntArray(frame.width, frame.height)
+
+	for x := 0; x < frame.width; x++ {
+		for y := 0; y < frame.height; y++ {
+			ret[x][y] = 9999
+			for point := range sources {
+				dx := Abs(point.X - x)
+				dy := Abs(point.Y - y)
+				if frame.width - dx < dx { dx = frame.width - dx }
+				if frame.height - dy < dy { dy = frame.height - dy }
+				if dx + dy < ret[x][y] {
+					ret[x][y] = dx + dy
+				}
+			}
+		}
+	}
+
+	return ret
+}
@@ -1,7 +1,6 @@
 package core
 
 import (
-	"fmt"
 	"../logging"
 )
 
@@ -15,6 +14,12 @@ type WealthMap struct {
 
 func NewWealthMap(frame *Frame) *WealthMap {
 
+	if WEALTH_MAP_RADIUS <= frame.WealthTable().MaxRadius {
+		return frame.WealthTable().Map(WEALTH_MAP_RADIUS)
+	}
+
+	// The map is too small for the table, so do it the slow way...
+
 	self := new(WealthMap)
 
 	self.Values = Make2dIntArray(frame.Width(), frame.Height())
@@ -58,8 +63,7 @@ func (self *WealthMap) propagate(ox, oy, value int, radius int) {
 func (self *WealthMap) Flog(turn int) {
 	for x := 0; x < len(self.Values); x++ {
 		for y := 0; y < len(self.Values[0]); y++ {
-			s := fmt.Sprintf("Wealth: %v", self.Values[x][y])
-			logging.Flog(turn, x, y, s, "")
+			logging.FlogValue(turn, x, y, "wealth", float64(self.Values[x][y]))
 		}
 	}
 }
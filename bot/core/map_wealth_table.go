@@ -0,0 +1,136 @@
+package core
+
+// A WealthTable answers "how much halite is within distance r of (x,y)" in O(1)
+// for any radius r up to MaxRadius, using a summed-area table in rotated
+// coordinates. Rotating by 45 degrees (u = x + y, v = x - y) turns the Manhattan
+// diamond into an axis-aligned square, which a normal summed-area table handles.
+//
+// To cope with the torus, the halite grid is first tiled out by MaxRadius cells
+// on every side, so a diamond never needs to wrap. Diamonds wider than the map
+// would count some cells twice, so MaxRadius is limited to (min(width, height) - 1) / 2;
+// bigger radii are answered cell by cell, in O(width * height).
+
+type WealthTable struct {
+	MaxRadius		int
+	width			int
+	height			int
+	side			int			// Side of the rotated (square) table
+	offset			int			// Added to v so it's never negative
+	sums			[][]int		// sums[u][v] is the total of rotated cells [0, u) x [0, v)
+}
+
+func NewWealthTable(frame *Frame) *WealthTable {
+
+	width := frame.Width()
+	height := frame.Height()
+
+	self := new(WealthTable)
+	self.width = width
+	self.height = height
+
+	self.MaxRadius = width
+	if height < width {
+		self.MaxRadius = height
+	}
+	self.MaxRadius = (self.MaxRadius - 1) / 2
+
+	pad := self.MaxRadius
+	ext_width := width + pad * 2
+	ext_height := height + pad * 2
+
+	self.offset = ext_height - 1
+	self.side = ext_width + ext_height - 1
+
+	self.sums = Make2dIntArray(self.side + 1, self.side + 1)
+
+	// Place each cell of the extended grid at its rotated position.
+	// Cells of the wrong parity are left as zero.
+
+	for i := 0; i < ext_width; i++ {
+		for j := 0; j < ext_height; j++ {
+			u := i + j
+			v := i - j + self.offset
+			self.sums[u + 1][v + 1] = frame.HaliteAtFast(Mod(i - pad, width), Mod(j - pad, height))
+		}
+	}
+
+	for u := 1; u <= self.side; u++ {
+		for v := 1; v <= self.side; v++ {
+			self.sums[u][v] += self.sums[u - 1][v] + self.sums[u][v - 1] - self.sums[u - 1][v - 1]
+		}
+	}
+
+	return self
+}
+
+func (self *WealthTable) Sum(x, y, radius int) int {
+
+	// Total halite in the cells within Manhattan distance <radius> of (x, y).
+
+	if radius < 0 {
+		return 0
+	}
+
+	if radius > self.MaxRadius {
+		return self.slow_sum(x, y, radius)
+	}
+
+	i := Mod(x, self.width) + self.MaxRadius
+	j := Mod(y, self.height) + self.MaxRadius
+
+	u := i + j
+	v := i - j + self.offset
+
+	u1, u2 := u - radius, u + radius + 1
+	v1, v2 := v - radius, v + radius + 1
+
+	return self.sums[u2][v2] - self.sums[u1][v2] - self.sums[u2][v1] + self.sums[u1][v1]
+}
+
+func (self *WealthTable) slow_sum(x, y, radius int) int {
+
+	// The diamond overlaps itself on the torus, so visit each cell once.
+	// A single cell is just a diamond of radius 0.
+
+	x = Mod(x, self.width)
+	y = Mod(y, self.height)
+
+	ret := 0
+
+	for i := 0; i < self.width; i++ {
+		for j := 0; j < self.height; j++ {
+
+			dx := Abs(i - x)
+			if dx > self.width - dx {
+				dx = self.width - dx
+			}
+
+			dy := Abs(j - y)
+			if dy > self.height - dy {
+				dy = self.height - dy
+			}
+
+			if dx + dy <= radius {
+				ret += self.Sum(i, j, 0)
+			}
+		}
+	}
+
+	return ret
+}
+
+func (self *WealthTable) Map(radius int) *WealthMap {
+
+	// A WealthMap of the given radius, made in O(width * height).
+
+	ret := new(WealthMap)
+	ret.Values = Make2dIntArray(self.width, self.height)
+
+	for x := 0; x < self.width; x++ {
+		for y := 0; y < self.height; y++ {
+			ret.Values[x][y] = self.Sum(x, y, radius)
+		}
+	}
+
+	return ret
+}
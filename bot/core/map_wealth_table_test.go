@@ -0,0 +1,95 @@
+package core
+
+import (
+	"math/rand"
+	"testing"
+)
+
+func TestWealthTableSum(t *testing.T) {
+
+	// Sum() against adding up every cell within range, each counted once, on
+	// square and non-square maps, with radii up to and well past MaxRadius.
+
+	sizes := []struct {
+		width			int
+		height			int
+	}{
+		{32, 32},
+		{40, 24},
+		{24, 40},
+		{7, 5},
+	}
+
+	rng := rand.New(rand.NewSource(1))
+
+	for _, size := range sizes {
+
+		frame := &Frame{width: size.width, height: size.height}
+		frame.halite = Make2dIntArray(size.width, size.height)
+
+		for x := 0; x < size.width; x++ {
+			for y := 0; y < size.height; y++ {
+				frame.halite[x][y] = rng.Intn(1000)
+			}
+		}
+
+		table := NewWealthTable(frame)
+
+		radii := []int{-1, 0, 1, 2, table.MaxRadius, table.MaxRadius + 1, size.width / 2, size.width / 2 + 1, size.height / 2 + 1, size.width + size.height}
+
+		for _, radius := range radii {
+
+			for _, pos := range []Point{{0, 0}, {size.width - 1, size.height - 1}, {size.width / 2, 1}, {3, size.height - 2}, {-2, size.height + 3}} {
+
+				got := table.Sum(pos.X, pos.Y, radius)
+				expected := diamond_sum(frame, pos.X, pos.Y, radius)
+
+				if got != expected {
+					t.Errorf("%dx%d, radius %d at %v: got %d, expected %d", size.width, size.height, radius, pos, got, expected)
+				}
+			}
+
+			if radius < 0 {
+				continue
+			}
+
+			m := table.Map(radius)
+
+			for x := 0; x < size.width; x++ {
+				for y := 0; y < size.height; y++ {
+					if m.Values[x][y] != diamond_sum(frame, x, y, radius) {
+						t.Fatalf("%dx%d, radius %d: Map() wrong at %d,%d", size.width, size.height, radius, x, y)
+					}
+				}
+			}
+		}
+	}
+}
+
+func diamond_sum(frame *Frame, x, y, radius int) int {
+
+	// The halite of every cell within <radius> of (x, y) on the torus.
+
+	x = Mod(x, frame.width)
+	y = Mod(y, frame.height)
+
+	ret := 0
+
+	for i := 0; i < frame.width; i++ {
+		for j := 0; j < frame.height; j++ {
+			dx := Abs(i - x)
+			dy := Abs(j - y)
+			if frame.width - dx < dx {
+				dx = frame.width - dx
+			}
+			if frame.height - dy < dy {
+				dy = frame.height - dy
+			}
+			if dx + dy <= radius {
+				ret += frame.halite[i][j]
+			}
+		}
+	}
+
+	return ret
+}
@@ -0,0 +1,205 @@
+package core
+
+// The MovePredictor learns, for each player, how their ships tend to move in
+// various situations, by watching what actually happened between parses.
+// It lives across the whole game and is shared (not copied) by simulated
+// frames, which never update it -- only Parse() does.
+//
+// A situation is a bucket made from the ship's cargo and whether it's sitting
+// on a rich cell. A move is classified relative to the ship's nearest dropoff:
+// staying put, heading toward it, heading away, or neither.
+
+const (
+	PRED_STAY = iota
+	PRED_TOWARD
+	PRED_AWAY
+	PRED_SIDEWAYS
+	PRED_CATEGORIES
+)
+
+const (
+	PRED_CARGO_BUCKETS = 4
+	PRED_BUCKETS = PRED_CARGO_BUCKETS * 2
+	PRED_PRIOR_WEIGHT = 5.0
+)
+
+type MovePredictor struct {
+	counts				map[int]*[PRED_BUCKETS][PRED_CATEGORIES]float64		// pid --> bucket --> category --> count
+	observations		map[int]int
+}
+
+func NewMovePredictor() *MovePredictor {
+	ret := new(MovePredictor)
+	ret.counts = make(map[int]*[PRED_BUCKETS][PRED_CATEGORIES]float64)
+	ret.observations = make(map[int]int)
+	return ret
+}
+
+func (self *MovePredictor) player_counts(pid int) *[PRED_BUCKETS][PRED_CATEGORIES]float64 {
+	if self.counts[pid] == nil {
+		self.counts[pid] = new([PRED_BUCKETS][PRED_CATEGORIES]float64)
+	}
+	return self.counts[pid]
+}
+
+func pred_bucket(ship *Ship) int {
+
+	cargo := ship.Halite * PRED_CARGO_BUCKETS / (ship.Frame.Constants.MAX_ENERGY + 1)
+
+	rich := 0
+	if ship.HaliteAt() > ship.Frame.AverageGroundHalite() {
+		rich = 1
+	}
+
+	return cargo * 2 + rich
+}
+
+func pred_category(ship *Ship, move string) int {
+
+	if move == "o" || move == "" || move == "c" {
+		return PRED_STAY
+	}
+
+	dropoff := ship.NearestDropoff()
+	before := ship.Dist(dropoff)
+	after := Dist(dropoff, ship.LocationAfterMove(move))
+
+	if after < before {
+		return PRED_TOWARD
+	} else if after > before {
+		return PRED_AWAY
+	}
+	return PRED_SIDEWAYS
+}
+
+func (self *MovePredictor) Observe(old_frame, new_frame *Frame) {
+
+	// Learn from every ship that survived from <old_frame> into <new_frame>
+	// and which actually had a choice about moving.
+
+	for _, item := range old_frame.ships {
+
+		ship := new_frame.ship_id_lookup[item.Sid]
+
+		if ship == nil {
+			continue
+		}
+
+		// In Parse() the old ships still point at the (now updated) frame, so
+		// use a copy that sees the old halite and dropoffs...
+
+		old_ship := *item
+		old_ship.Frame = old_frame
+
+		if old_ship.Halite < old_ship.MoveCost() {
+			continue
+		}
+
+		dx, dy := DxDy(&old_ship, ship)
+
+		var move string
+
+		switch {
+		case dx == 0 && dy == 0:
+			move = "o"
+		case dx == 1 && dy == 0:
+			move = "e"
+		case dx == -1 && dy == 0:
+			move = "w"
+		case dx == 0 && dy == 1:
+			move = "s"
+		case dx == 0 && dy == -1:
+			move = "n"
+		default:
+			continue
+		}
+
+		counts := self.player_counts(old_ship.Owner)
+		counts[pred_bucket(&old_ship)][pred_category(&old_ship, move)] += 1
+		self.observations[old_ship.Owner]++
+	}
+}
+
+func (self *MovePredictor) Observations(pid int) int {
+	return self.observations[pid]
+}
+
+func (self *MovePredictor) Odds(ship *Ship) map[string]float64 {
+
+	if ship.Halite < ship.MoveCost() {
+		return map[string]float64{"o": 1.0}
+	}
+
+	counts := self.player_counts(ship.Owner)[pred_bucket(ship)]
+
+	// Spread each category's count over the moves that fall into it, ignoring
+	// categories with no possible moves. Ship.CrudeMoveOdds() acts as a prior worth
+	// PRED_PRIOR_WEIGHT observations, so with none we give exactly its guesses.
+
+	moves := []string{"o", "e", "w", "s", "n"}
+	var members [PRED_CATEGORIES]int
+
+	for _, move := range moves {
+		members[pred_category(ship, move)]++
+	}
+
+	total := 0.0
+	for category := 0; category < PRED_CATEGORIES; category++ {
+		if members[category] > 0 {
+			total += counts[category]
+		}
+	}
+
+	prior := ship.CrudeMoveOdds()
+	ret := make(map[string]float64)
+
+	for _, move := range moves {
+		category := pred_category(ship, move)
+		ret[move] = (counts[category] / float64(members[category]) + prior[move] * PRED_PRIOR_WEIGHT) / (total + PRED_PRIOR_WEIGHT)
+	}
+
+	return ret
+}
+
+// ------------------------------------------------------------
+// The risk map gives, for each cell, the probability that at least one enemy
+// ship (from the point of view of the current pid) will be there next turn.
+
+type EnemyRiskMap struct {
+	Values			[][]float64
+}
+
+func NewEnemyRiskMap(frame *Frame) *EnemyRiskMap {
+
+	self := new(EnemyRiskMap)
+
+	safe := Make2dFloatArray(frame.Width(), frame.Height())		// Probability of no enemy
+
+	for x := 0; x < frame.Width(); x++ {
+		for y := 0; y < frame.Height(); y++ {
+			safe[x][y] = 1.0
+		}
+	}
+
+	for _, ship := range frame.EnemyShips() {
+		odds := ship.MoveOdds()
+		for _, move := range []string{"o", "e", "w", "s", "n"} {		// Not map order, so the products are repeatable
+			p, ok := odds[move]
+			if ok == false {
+				continue
+			}
+			loc := ship.LocationAfterMove(move)
+			safe[loc.X][loc.Y] *= 1 - p
+		}
+	}
+
+	self.Values = Make2dFloatArray(frame.Width(), frame.Height())
+
+	for x := 0; x < frame.Width(); x++ {
+		for y := 0; y < frame.Height(); y++ {
+			self.Values[x][y] = 1 - safe[x][y]
+		}
+	}
+
+	return self
+}
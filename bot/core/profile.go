@@ -0,0 +1,231 @@
+package core
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// Each player gets a profile, accumulated over the whole game by comparing
+// consecutive parsed frames. Like the MovePredictor, profiles last the whole
+// game and are only ever updated by Parse().
+
+const (
+	PROFILE_RAM_GAP = 300			// How much more the victim must be carrying for a collision to count as a ram
+	PROFILE_RAMMER_SAMPLE = 3		// Enemy collisions needed before we judge a player
+	PROFILE_RAMMER_RATE = 0.5		// Fraction of those which must be rams
+)
+
+type PlayerProfile struct {
+	Pid						int
+
+	Deliveries				[]int		// Cargo of each ship as it arrived at a dropoff
+	Spawns					int
+	LastSpawnTurn			int			// -1 if never
+	DropoffTurns			[]int		// Turns on which new dropoffs appeared
+
+	ShipsLost				int			// Every ship that vanished other than by building
+	SelfCollisions			int			// Ships lost to collisions with our own ships (as far as we can tell)
+	EnemyCollisions			int			// Ships lost to collisions with other players' ships
+	Rams					int			// Enemy collisions where this player's ship moved onto a much heavier one
+
+	Dodges					int			// Course changes for safety (only the AI sets these, so ours only)
+	AvoidedCollisions		int			// Dodges where an enemy ship did end up in the cell we'd have moved to
+}
+
+func NewPlayerProfile(pid int) *PlayerProfile {
+	return &PlayerProfile{Pid: pid, LastSpawnTurn: -1}
+}
+
+func (self *PlayerProfile) ReturnThreshold() int {
+
+	// Our best guess at the cargo this player returns with: the median delivery.
+
+	if len(self.Deliveries) == 0 {
+		return 0
+	}
+
+	sorted := append([]int(nil), self.Deliveries...)
+	sort.Ints(sorted)
+	return sorted[len(sorted) / 2]
+}
+
+func (self *PlayerProfile) Rammer() bool {
+
+	// Whether this player's ships have been seen deliberately (as far as we can
+	// tell) hitting heavier enemies, often enough that it looks like policy.
+
+	if self.EnemyCollisions < PROFILE_RAMMER_SAMPLE {
+		return false
+	}
+
+	return float64(self.Rams) / float64(self.EnemyCollisions) >= PROFILE_RAMMER_RATE
+}
+
+func (self *PlayerProfile) String() string {
+
+	var dropoff_strings []string
+	for _, t := range self.DropoffTurns {
+		dropoff_strings = append(dropoff_strings, fmt.Sprintf("%d", t))
+	}
+
+	return fmt.Sprintf("Player %d: return ~%d (%d deliveries), spawned %d (last t %d), dropoffs at [%s], lost %d (self %d, enemy %d), rams %d (rammer: %v), dodges %d (avoided %d)",
+		self.Pid, self.ReturnThreshold(), len(self.Deliveries), self.Spawns, self.LastSpawnTurn, strings.Join(dropoff_strings, " "),
+		self.ShipsLost, self.SelfCollisions, self.EnemyCollisions, self.Rams, self.Rammer(), self.Dodges, self.AvoidedCollisions)
+}
+
+// ------------------------------------------------------------
+
+type Profiler struct {
+	profiles		map[int]*PlayerProfile
+}
+
+func NewProfiler() *Profiler {
+	ret := new(Profiler)
+	ret.profiles = make(map[int]*PlayerProfile)
+	return ret
+}
+
+func (self *Profiler) Profile(pid int) *PlayerProfile {
+	if self.profiles[pid] == nil {
+		self.profiles[pid] = NewPlayerProfile(pid)
+	}
+	return self.profiles[pid]
+}
+
+func (self *Profiler) Observe(old_frame, new_frame *Frame) {
+
+	// Deliveries and spawns...
+
+	for _, ship := range new_frame.ships {
+
+		old_ship := old_frame.ship_id_lookup[ship.Sid]
+
+		if old_ship == nil {
+			profile := self.Profile(ship.Owner)
+			profile.Spawns++
+			profile.LastSpawnTurn = new_frame.turn
+			continue
+		}
+
+		if old_ship.Halite > 0 && ship.Halite == 0 && new_frame.PlayerCanDropoffAt(ship.Owner, ship) {
+			if old_ship.X != ship.X || old_ship.Y != ship.Y {
+				self.Profile(ship.Owner).Deliveries = append(self.Profile(ship.Owner).Deliveries, old_ship.Halite)
+			}
+		}
+	}
+
+	// New dropoffs...
+
+	for pid := 0; pid < new_frame.players; pid++ {
+		added := len(new_frame.Dropoffs(pid)) - len(old_frame.Dropoffs(pid))
+		for n := 0; n < added; n++ {
+			self.Profile(pid).DropoffTurns = append(self.Profile(pid).DropoffTurns, new_frame.turn)
+		}
+	}
+
+	// Course changes, which avoided a collision if an enemy is now where we would have been...
+
+	for _, old_ship := range old_frame.ships {
+		if old_ship.dodged_ok == false {
+			continue
+		}
+		profile := self.Profile(old_ship.Owner)
+		profile.Dodges++
+		if other := new_frame.ship_xy_lookup[old_ship.dodged]; other != nil && other.Owner != old_ship.Owner {
+			profile.AvoidedCollisions++
+		}
+	}
+
+	// Lost ships. A ship that built a dropoff left one where it stood...
+
+	var lost []*Ship
+
+	for _, old_ship := range old_frame.ships {
+		if new_frame.ship_id_lookup[old_ship.Sid] != nil {
+			continue
+		}
+		if new_frame.PlayerCanDropoffAt(old_ship.Owner, old_ship) && old_frame.PlayerCanDropoffAt(old_ship.Owner, old_ship) == false {
+			continue
+		}
+		lost = append(lost, old_ship)
+		self.Profile(old_ship.Owner).ShipsLost++
+	}
+
+	// We don't see the moves, so guess that lost ships which could have reached
+	// a common cell collided with each other.
+
+	reach := make(map[Point][]*Ship)
+
+	for _, ship := range lost {
+		for _, move := range []string{"o", "e", "w", "s", "n"} {
+			dx, dy := StringToDxDy(move)
+			loc := Point{Mod(ship.X + dx, new_frame.width), Mod(ship.Y + dy, new_frame.height)}
+			reach[loc] = append(reach[loc], ship)
+		}
+	}
+
+	for _, ship := range lost {
+
+		self_hit := false
+		var enemy_hit *Ship
+
+		for _, move := range []string{"o", "e", "w", "s", "n"} {
+			dx, dy := StringToDxDy(move)
+			loc := Point{Mod(ship.X + dx, new_frame.width), Mod(ship.Y + dy, new_frame.height)}
+			for _, other := range reach[loc] {
+				if other == ship {
+					continue
+				}
+				if other.Owner == ship.Owner {
+					self_hit = true
+				} else if enemy_hit == nil {
+					enemy_hit = other
+				}
+			}
+		}
+
+		profile := self.Profile(ship.Owner)
+
+		if enemy_hit != nil {
+			profile.EnemyCollisions++
+			if rammed(old_frame, new_frame, ship, enemy_hit) {
+				profile.Rams++
+			}
+		} else if self_hit {
+			profile.SelfCollisions++
+		}
+	}
+}
+
+func rammed(old_frame, new_frame *Frame, ship, victim *Ship) bool {
+
+	// Whether <ship> chose to hit <victim>: it was carrying much less, it wasn't
+	// dodging something else, and it moved onto the victim where it sat. The
+	// last we can tell because a wreck's cargo is dumped where it happened (at a
+	// dropoff it goes to the owner instead, so those never count).
+
+	if victim.Halite - ship.Halite < PROFILE_RAM_GAP || ship.dodged_ok {
+		return false
+	}
+
+	dumped := new_frame.halite[victim.X][victim.Y] - old_frame.halite[victim.X][victim.Y]
+
+	return dumped >= victim.Halite + ship.Halite
+}
+
+func (self *Frame) Profile(pid int) *PlayerProfile {		// Maybe nil
+	if self.profiler == nil {
+		return nil
+	}
+	return self.profiler.Profile(pid)
+}
+
+func (self *Frame) LogProfiles() {
+	if self.profiler == nil {
+		return
+	}
+	for pid := 0; pid < self.players; pid++ {
+		self.Log("%v", self.profiler.Profile(pid))
+	}
+}
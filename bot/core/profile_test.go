@@ -0,0 +1,126 @@
+package core
+
+import (
+	"testing"
+)
+
+func TestRammerProfile(t *testing.T) {
+
+	// Each case is a collision between a player 0 ship and a player 1 ship next
+	// to it, seen PROFILE_RAMMER_SAMPLE times; only a real ram makes 0 a rammer.
+
+	cases := []struct {
+		name			string
+		light			int
+		heavy			int
+		at_heavy		bool		// Whether the wreck was in the heavy ship's cell, i.e. the light ship moved
+		dodging			bool
+		rammer			bool
+	}{
+		{"ram",						100,	800,	true,	false,	true},
+		{"rammed by the heavy",		100,	800,	false,	false,	false},
+		{"small gap",				500,	600,	true,	false,	false},
+		{"dodging",					100,	800,	true,	true,	false},
+	}
+
+	for _, c := range cases {
+
+		old_frame, new_frame := collision_frames(t, c.light, c.heavy, c.at_heavy)
+
+		if c.dodging {
+			old_frame.ships[0].SetDodged(Point{0, 0})
+		}
+
+		profiler := NewProfiler()
+
+		for n := 0; n < PROFILE_RAMMER_SAMPLE; n++ {
+			profiler.Observe(old_frame, new_frame)
+		}
+
+		if profiler.Profile(0).EnemyCollisions != PROFILE_RAMMER_SAMPLE {
+			t.Errorf("%s: %d enemy collisions seen, expected %d", c.name, profiler.Profile(0).EnemyCollisions, PROFILE_RAMMER_SAMPLE)
+		}
+
+		if profiler.Profile(0).Rammer() != c.rammer {
+			t.Errorf("%s: rammer %v, expected %v", c.name, profiler.Profile(0).Rammer(), c.rammer)
+		}
+
+		if profiler.Profile(1).Rammer() {
+			t.Errorf("%s: heavy ship's owner flagged as a rammer", c.name)
+		}
+	}
+}
+
+func TestNoRammersInFixture(t *testing.T) {
+
+	// The fixture game is between ordinary bots, which collide now and then but
+	// never ram on purpose.
+
+	var last *Frame
+
+	play_back(t, "testdata/game-32-4p.txt", func(frame *Frame) {
+		last = frame
+	})
+
+	sampled := 0
+
+	for pid := 0; pid < last.Players(); pid++ {
+		profile := last.Profile(pid)
+		if profile.Rammer() {
+			t.Errorf("%v", profile)
+		}
+		if profile.EnemyCollisions >= PROFILE_RAMMER_SAMPLE {
+			sampled++
+		}
+	}
+
+	if sampled == 0 {
+		t.Fatalf("no player had enough collisions to be judged")
+	}
+}
+
+func collision_frames(t *testing.T, light, heavy int, at_heavy bool) (*Frame, *Frame) {
+
+	// Two frames from the snapshot fixture: before, with just a player 0 ship
+	// next to a player 1 ship, and after, with both wrecked.
+
+	snapshot := read_snapshot(t, SNAPSHOT_FIXTURE)
+
+	occupied := make(map[Point]bool)
+	for _, dropoff := range snapshot.Dropoffs {
+		occupied[Point{dropoff.X, dropoff.Y}] = true
+	}
+
+	light_pos := Point{0, 0}
+	for occupied[light_pos] || occupied[Point{light_pos.X + 1, light_pos.Y}] {
+		light_pos.Y++
+	}
+	heavy_pos := Point{light_pos.X + 1, light_pos.Y}
+
+	snapshot.Ships = []SnapshotShip{
+		SnapshotShip{Owner: 0, Sid: snapshot.HighestSid + 1, X: light_pos.X, Y: light_pos.Y, Halite: light},
+		SnapshotShip{Owner: 1, Sid: snapshot.HighestSid + 2, X: heavy_pos.X, Y: heavy_pos.Y, Halite: heavy},
+	}
+	snapshot.HighestSid += 2
+
+	old_frame, err := snapshot.Frame()
+	if err != nil {
+		t.Fatalf("%v", err)
+	}
+
+	wreck := light_pos
+	if at_heavy {
+		wreck = heavy_pos
+	}
+
+	snapshot.Ships = nil
+	snapshot.Turn++
+	snapshot.Halite[wreck.Y * snapshot.Width + wreck.X] += light + heavy
+
+	new_frame, err := snapshot.Frame()
+	if err != nil {
+		t.Fatalf("%v", err)
+	}
+
+	return old_frame, new_frame
+}
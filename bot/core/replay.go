@@ -0,0 +1,461 @@
+package core
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"os/exec"
+	"sort"
+	"strings"
+)
+
+// Records a game played by the simulator as a Halite III replay, in the same
+// JSON schema as the engine writes with --no-compression, so that it can be
+// opened in Fluorine or the official visualiser. Usage:
+//
+//		replay := hal.NewReplay(frame, "name")
+//		for ... {
+//			(set every player's commands)
+//			next := frame.SimGen()
+//			replay.Record(frame, next)
+//			frame = next
+//		}
+//		replay.Write("replays/foo.hlt")		// Compressed with zstd if the name ends in .zst or .hlt
+//
+// Full frame N holds the entities at the start of turn N, that turn's moves,
+// and the events, changed cells and energies that resulted. Frame 0 is the
+// initial state, with no moves.
+
+const REPLAY_FILE_VERSION = 3
+
+// Go has no zstd of its own, so compressed replays need the command line tool.
+
+var ErrNoZstd = errors.New("the zstd command isn't on PATH; it's needed for compressed replays (use a .json name to write one uncompressed)")
+
+func CheckZstd() error {
+	if _, err := exec.LookPath("zstd"); err != nil {
+		return ErrNoZstd
+	}
+	return nil
+}
+
+func ReplayCompressed(filename string) bool {			// Whether Write() will compress, going by the name
+	lower := strings.ToLower(filename)
+	return strings.HasSuffix(lower, ".hlt") || strings.HasSuffix(lower, ".zst")
+}
+
+type Location struct {
+	X					int						`json:"x"`
+	Y					int						`json:"y"`
+}
+
+type ReplayCell struct {
+	Energy				int						`json:"energy"`
+}
+
+type ReplayMap struct {
+	Width				int						`json:"width"`
+	Height				int						`json:"height"`
+	MapGenerator		string					`json:"map_generator"`
+	Grid				[][]ReplayCell			`json:"grid"`					// [y][x]
+}
+
+type ReplayPlayer struct {
+	PlayerId			int						`json:"player_id"`
+	Name				string					`json:"name"`
+	Energy				int						`json:"energy"`
+	FactoryLocation		Location				`json:"factory_location"`
+	Entities			[]interface{}			`json:"entities"`
+}
+
+type ReplayCellInfo struct {
+	X					int						`json:"x"`
+	Y					int						`json:"y"`
+	Production			int						`json:"production"`
+}
+
+type ReplayEntity struct {
+	X					int						`json:"x"`
+	Y					int						`json:"y"`
+	Energy				int						`json:"energy"`
+	IsInspired			bool					`json:"is_inspired"`
+}
+
+type ReplayMove struct {
+	Type				string					`json:"type"`					// "m", "g" or "c"
+	Id					*int					`json:"id,omitempty"`
+	Direction			string					`json:"direction,omitempty"`
+}
+
+type ReplayEvent struct {
+	Type				string					`json:"type"`					// "spawn", "construct" or "shipwreck"
+	Location			Location				`json:"location"`
+	OwnerId				*int					`json:"owner_id,omitempty"`
+	Id					*int					`json:"id,omitempty"`
+	Energy				*int					`json:"energy,omitempty"`
+	Ships				[]int					`json:"ships,omitempty"`
+}
+
+type ReplayFrame struct {
+	Events				[]ReplayEvent							`json:"events"`
+	Cells				[]ReplayCellInfo						`json:"cells"`
+	Entities			map[string]map[string]ReplayEntity		`json:"entities"`
+	Energy				map[string]int							`json:"energy"`
+	Deposited			map[string]int							`json:"deposited"`
+	Moves				map[string][]ReplayMove					`json:"moves"`
+}
+
+type PlayerStatistics struct {
+	PlayerId			int						`json:"player_id"`
+	Rank				int						`json:"rank"`
+	LastTurnAlive		int						`json:"last_turn_alive"`
+	FinalProduction		int						`json:"final_production"`
+	TotalProduction		int						`json:"total_production"`
+	TotalMined			int						`json:"total_mined"`
+	TotalDropped		int						`json:"total_dropped"`
+	CarriedAtEnd		int						`json:"carried_at_end"`
+	MiningEfficiency	float64					`json:"mining_efficiency"`
+	NumberDropoffs		int						`json:"number_dropoffs"`
+	ShipsSpawned		int						`json:"ships_spawned"`
+	ShipsPeak			int						`json:"ships_peak"`
+	LastTurnShipSpawn	int						`json:"last_turn_ship_spawn"`
+	SelfCollisions		int						`json:"self_collisions"`
+	AllCollisions		int						`json:"all_collisions"`
+	DropoffCollisions	int						`json:"dropoff_collisions"`
+}
+
+type GameStatistics struct {
+	NumberTurns			int						`json:"number_turns"`
+	PlayerStatistics	[]*PlayerStatistics		`json:"player_statistics"`
+}
+
+type Replay struct {
+	EngineVersion		string					`json:"ENGINE_VERSION"`
+	GameConstants		Constants				`json:"GAME_CONSTANTS"`
+	ReplayFileVersion	int						`json:"REPLAY_FILE_VERSION"`
+	GameStatistics		GameStatistics			`json:"game_statistics"`
+	MapGeneratorSeed	int64					`json:"map_generator_seed"`
+	NumberOfPlayers		int						`json:"number_of_players"`
+	Players				[]ReplayPlayer			`json:"players"`
+	ProductionMap		ReplayMap				`json:"production_map"`
+	FullFrames			[]*ReplayFrame			`json:"full_frames"`
+
+	final				*Frame
+}
+
+func int_ptr(i int) *int {
+	return &i
+}
+
+func NewReplay(frame *Frame, name string) *Replay {
+
+	// The frame should be the initial (turn 0) state.
+
+	ret := &Replay{
+		EngineVersion:		"gohalite3 simulator",
+		GameConstants:		frame.Constants,
+		ReplayFileVersion:	REPLAY_FILE_VERSION,
+		MapGeneratorSeed:	frame.Constants.GameSeed,
+		NumberOfPlayers:	frame.players,
+		final:				frame,
+	}
+
+	for pid := 0; pid < frame.players; pid++ {
+		factory := frame.Factory(pid)
+		ret.Players = append(ret.Players, ReplayPlayer{
+			PlayerId:			pid,
+			Name:				name,
+			Energy:				frame.budgets[pid],
+			FactoryLocation:	Location{factory.X, factory.Y},
+			Entities:			[]interface{}{},
+		})
+		ret.GameStatistics.PlayerStatistics = append(ret.GameStatistics.PlayerStatistics, &PlayerStatistics{PlayerId: pid, LastTurnShipSpawn: -1})
+	}
+
+	ret.ProductionMap = ReplayMap{Width: frame.width, Height: frame.height, MapGenerator: "basic"}
+
+	for y := 0; y < frame.height; y++ {
+		var row []ReplayCell
+		for x := 0; x < frame.width; x++ {
+			row = append(row, ReplayCell{frame.halite[x][y]})
+		}
+		ret.ProductionMap.Grid = append(ret.ProductionMap.Grid, row)
+	}
+
+	initial := new_replay_frame()
+	for pid := 0; pid < frame.players; pid++ {
+		key := fmt.Sprintf("%d", pid)
+		initial.Energy[key] = frame.budgets[pid]
+		initial.Deposited[key] = 0
+	}
+	ret.FullFrames = append(ret.FullFrames, initial)
+
+	return ret
+}
+
+func new_replay_frame() *ReplayFrame {
+	return &ReplayFrame{
+		Events:		[]ReplayEvent{},
+		Cells:		[]ReplayCellInfo{},
+		Entities:	make(map[string]map[string]ReplayEntity),
+		Energy:		make(map[string]int),
+		Deposited:	make(map[string]int),
+		Moves:		make(map[string][]ReplayMove),
+	}
+}
+
+func (self *Replay) Record(old_frame, new_frame *Frame) {
+
+	// <old_frame> must still have the commands that SimGen() used to make <new_frame>.
+
+	rf := new_replay_frame()
+	previous := self.FullFrames[len(self.FullFrames) - 1]
+	stats := self.GameStatistics.PlayerStatistics
+
+	for pid := 0; pid < old_frame.players; pid++ {
+		key := fmt.Sprintf("%d", pid)
+		rf.Entities[key] = make(map[string]ReplayEntity)
+		rf.Moves[key] = []ReplayMove{}
+	}
+
+	// Entities and moves...
+
+	constructed := make(map[int]bool)
+	destinations := make(map[int]Point)
+
+	for _, ship := range old_frame.ships {
+
+		key := fmt.Sprintf("%d", ship.Owner)
+		rf.Entities[key][fmt.Sprintf("%d", ship.Sid)] = ReplayEntity{ship.X, ship.Y, ship.Halite, ship.Inspired}
+
+		destinations[ship.Sid] = ship.Point()
+
+		switch ship.Command {
+
+		case "c":
+			rf.Moves[key] = append(rf.Moves[key], ReplayMove{Type: "c", Id: int_ptr(ship.Sid)})
+			constructed[ship.Sid] = true
+			rf.Events = append(rf.Events, ReplayEvent{Type: "construct", Location: Location{ship.X, ship.Y}, OwnerId: int_ptr(ship.Owner), Id: int_ptr(ship.Sid)})
+			stats[ship.Owner].NumberDropoffs++
+
+		case "n", "s", "e", "w":
+			rf.Moves[key] = append(rf.Moves[key], ReplayMove{Type: "m", Id: int_ptr(ship.Sid), Direction: ship.Command})
+			if ship.Halite >= ship.MoveCost() {
+				destinations[ship.Sid] = ship.LocationAfterMove(ship.Command)
+			}
+		}
+	}
+
+	for pid := 0; pid < old_frame.players; pid++ {
+		if old_frame.generate[pid] {
+			key := fmt.Sprintf("%d", pid)
+			rf.Moves[key] = append(rf.Moves[key], ReplayMove{Type: "g"})
+		}
+	}
+
+	// Spawns...
+
+	for _, ship := range new_frame.ships {
+		if old_frame.ship_id_lookup[ship.Sid] == nil {
+			rf.Events = append(rf.Events, ReplayEvent{Type: "spawn", Location: Location{ship.X, ship.Y}, OwnerId: int_ptr(ship.Owner), Id: int_ptr(ship.Sid), Energy: int_ptr(0)})
+			stats[ship.Owner].ShipsSpawned++
+			stats[ship.Owner].LastTurnShipSpawn = new_frame.turn
+		}
+	}
+
+	// Collisions, grouped by where they happened...
+
+	wrecks := make(map[Point][]*Ship)
+
+	for _, ship := range old_frame.ships {
+		if new_frame.ship_id_lookup[ship.Sid] == nil && constructed[ship.Sid] == false {
+			wrecks[destinations[ship.Sid]] = append(wrecks[destinations[ship.Sid]], ship)
+		}
+	}
+
+	var wreck_points []Point
+	for point, _ := range wrecks {
+		wreck_points = append(wreck_points, point)
+	}
+	sort.Slice(wreck_points, func(a, b int) bool {
+		return wreck_points[a].Y * old_frame.width + wreck_points[a].X < wreck_points[b].Y * old_frame.width + wreck_points[b].X
+	})
+
+	for _, point := range wreck_points {
+
+		var sids []int
+		owners := make(map[int]bool)
+
+		for _, ship := range wrecks[point] {
+			sids = append(sids, ship.Sid)
+			owners[ship.Owner] = true
+		}
+
+		rf.Events = append(rf.Events, ReplayEvent{Type: "shipwreck", Location: Location{point.X, point.Y}, Ships: sids})
+
+		for _, ship := range wrecks[point] {
+			stats[ship.Owner].AllCollisions++
+			if len(owners) == 1 {
+				stats[ship.Owner].SelfCollisions++
+			}
+			if new_frame.PlayerCanDropoffAt(ship.Owner, point) || old_frame.PlayerCanDropoffAt(ship.Owner, point) {
+				stats[ship.Owner].DropoffCollisions++
+			}
+		}
+	}
+
+	// Mining...
+
+	for _, ship := range new_frame.ships {
+		old_ship := old_frame.ship_id_lookup[ship.Sid]
+		if old_ship != nil && old_ship.X == ship.X && old_ship.Y == ship.Y && ship.Halite > old_ship.Halite {
+			stats[ship.Owner].TotalMined += ship.Halite - old_ship.Halite
+		}
+	}
+
+	// Cells, energy and deposits. Deposits are whatever budget change isn't explained by spending...
+
+	for _, point := range old_frame.changed_cells(new_frame) {
+		rf.Cells = append(rf.Cells, ReplayCellInfo{point.X, point.Y, new_frame.halite[point.X][point.Y]})
+	}
+
+	for pid := 0; pid < old_frame.players; pid++ {
+
+		key := fmt.Sprintf("%d", pid)
+
+		deposited := new_frame.budgets[pid] - old_frame.budgets[pid]
+
+		if old_frame.generate[pid] {
+			deposited += old_frame.Constants.NEW_ENTITY_ENERGY_COST
+		}
+
+		for _, ship := range old_frame.ships {
+			if ship.Owner == pid && constructed[ship.Sid] {
+				deposited += old_frame.Constants.DROPOFF_COST - ship.Halite - old_frame.halite[ship.X][ship.Y]
+			}
+		}
+
+		rf.Energy[key] = new_frame.budgets[pid]
+		rf.Deposited[key] = previous.Deposited[key] + deposited
+		stats[pid].TotalDropped += deposited
+
+		ships := len(new_frame.Ships(pid))
+		if ships > stats[pid].ShipsPeak {
+			stats[pid].ShipsPeak = ships
+		}
+		if ships > 0 {
+			stats[pid].LastTurnAlive = new_frame.turn
+		}
+	}
+
+	self.FullFrames = append(self.FullFrames, rf)
+	self.final = new_frame
+}
+
+func (self *Replay) finish() {
+
+	// Fill in the end-of-game statistics, and rank the players.
+
+	frame := self.final
+	stats := self.GameStatistics.PlayerStatistics
+
+	self.GameStatistics.NumberTurns = len(self.FullFrames) - 1
+
+	for pid, s := range stats {
+
+		s.FinalProduction = frame.budgets[pid]
+		s.TotalProduction = s.TotalDropped
+		s.CarriedAtEnd = 0
+
+		for _, ship := range frame.Ships(pid) {
+			s.CarriedAtEnd += ship.Halite
+		}
+
+		if s.TotalMined > 0 {
+			s.MiningEfficiency = float64(s.TotalDropped) / float64(s.TotalMined)
+		}
+	}
+
+	ranked := append([]*PlayerStatistics(nil), stats...)
+
+	sort.SliceStable(ranked, func(a, b int) bool {
+		if ranked[a].FinalProduction != ranked[b].FinalProduction {
+			return ranked[a].FinalProduction > ranked[b].FinalProduction
+		}
+		return ranked[a].LastTurnAlive > ranked[b].LastTurnAlive
+	})
+
+	for n, s := range ranked {
+		s.Rank = n + 1
+	}
+}
+
+func (self *Replay) Write(filename string) error {
+
+	if ReplayCompressed(filename) {
+		if err := CheckZstd(); err != nil {
+			return err
+		}
+	}
+
+	self.finish()
+
+	b, err := json.Marshal(self)
+	if err != nil {
+		return err
+	}
+
+	if ReplayCompressed(filename) == false {
+		return ioutil.WriteFile(filename, b, 0644)
+	}
+
+	cmd := exec.Command("zstd", "-q", "-f", "-o", filename)
+	cmd.Stdin = bytes.NewReader(b)
+
+	output, err := cmd.CombinedOutput()
+	if err != nil {
+		return fmt.Errorf("zstd: %v %s", err, strings.TrimSpace(string(output)))
+	}
+
+	return nil
+}
+
+func LoadReplay(filename string) (*Replay, error) {
+
+	// Reads either a plain JSON replay or a zstd compressed one (as the engine
+	// writes by default), whatever the extension.
+
+	b, err := ioutil.ReadFile(filename)
+	if err != nil {
+		return nil, err
+	}
+
+	if bytes.HasPrefix(b, []byte{0x28, 0xb5, 0x2f, 0xfd}) {			// zstd magic number
+
+		if err := CheckZstd(); err != nil {
+			return nil, err
+		}
+
+		cmd := exec.Command("zstd", "-q", "-d", "-c")
+		cmd.Stdin = bytes.NewReader(b)
+
+		var stderr bytes.Buffer
+		cmd.Stderr = &stderr
+
+		b, err = cmd.Output()
+		if err != nil {
+			return nil, fmt.Errorf("zstd: %v %s", err, strings.TrimSpace(stderr.String()))
+		}
+	}
+
+	ret := new(Replay)
+
+	err = json.Unmarshal(b, ret)
+	if err != nil {
+		return nil, err
+	}
+
+	return ret, nil
+}
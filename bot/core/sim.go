@@ -18,6 +18,7 @@ func (self *Frame) SimGen() *Frame {
 	*g = *self
 
 	g.ParseTime = time.Now()
+	g.simulated = true
 
 	g.turn += 1
 
@@ -299,6 +300,10 @@ func (self *Frame) SimGen() *Frame {
 
 	g.FixInspiration()
 
+	if g.throwaway == false {
+		g.inherit_maps(self, self.changed_cells(g))
+	}
+
 	return g
 }
 
@@ -320,4 +325,8 @@ func (self *Frame) DeleteEnemies() {
 	self.ship_xy_lookup = new_ship_xy_lookup
 	self.ship_id_lookup = new_ship_id_lookup
 	self.inspiration_map = nil
+	self.inspiration_forecast = nil
+	self.enemy_dist_map = nil
+	self.contest_map = nil
+	self.enemy_risk_map = nil
 }
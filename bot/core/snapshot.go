@@ -0,0 +1,304 @@
+package core
+
+import (
+	"compress/gzip"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"time"
+)
+
+// A Snapshot is everything needed to remake a Frame exactly, including the AI
+// state carried by ships from turn to turn, so a bug report can attach the
+// state that caused the bug and tests can start from a fixed position. Saved as
+// JSON, gzipped if the filename ends in .gz. Game-long things (the move
+// predictor and player profiles) are not saved; a loaded frame starts them fresh.
+//
+// Bump SNAPSHOT_VERSION whenever the format changes incompatibly.
+
+const SNAPSHOT_VERSION = 1
+
+type SnapshotShip struct {
+	Owner				int				`json:"owner"`
+	Sid					int				`json:"sid"`
+	X					int				`json:"x"`
+	Y					int				`json:"y"`
+	Halite				int				`json:"halite"`
+	Target				*Point			`json:"target,omitempty"`
+	Score				float32			`json:"score,omitempty"`
+	Returning			bool			`json:"returning,omitempty"`
+	FinalDash			bool			`json:"final_dash,omitempty"`
+	BuildSite			*Point			`json:"build_site,omitempty"`
+	Route				[]RouteStep		`json:"route,omitempty"`
+}
+
+func (self *SnapshotShip) points() []Point {			// Every cell in its AI state
+	var ret []Point
+	if self.Target != nil {
+		ret = append(ret, *self.Target)
+	}
+	if self.BuildSite != nil {
+		ret = append(ret, *self.BuildSite)
+	}
+	for _, step := range self.Route {
+		ret = append(ret, step.Point)
+	}
+	return ret
+}
+
+type SnapshotDropoff struct {
+	Owner				int				`json:"owner"`
+	X					int				`json:"x"`
+	Y					int				`json:"y"`
+	Factory				bool			`json:"factory,omitempty"`
+}
+
+type Snapshot struct {
+	Version				int					`json:"version"`
+	Constants			Constants			`json:"constants"`
+	Players				int					`json:"players"`
+	Width				int					`json:"width"`
+	Height				int					`json:"height"`
+	Pid					int					`json:"pid"`
+	TruePid				int					`json:"true_pid"`
+	Turn				int					`json:"turn"`
+	HighestSid			int					`json:"highest_sid"`
+	InitialGround		int					`json:"initial_ground"`
+	Budgets				[]int				`json:"budgets"`
+	Halite				[]int				`json:"halite"`			// Row by row: [y * width + x]
+	Ships				[]SnapshotShip		`json:"ships"`
+	Dropoffs			[]SnapshotDropoff	`json:"dropoffs"`			// Factories first, in player order
+}
+
+func (self *Frame) Snapshot() *Snapshot {
+
+	ret := &Snapshot{
+		Version:		SNAPSHOT_VERSION,
+		Constants:		self.Constants,
+		Players:		self.players,
+		Width:			self.width,
+		Height:			self.height,
+		Pid:			self.pid,
+		TruePid:		self.__true_pid,
+		Turn:			self.turn,
+		HighestSid:		self.highest_sid_seen,
+		InitialGround:	self.initial_ground_halite,
+		Budgets:		append([]int(nil), self.budgets...),
+		Halite:			make([]int, self.width * self.height),
+	}
+
+	for x := 0; x < self.width; x++ {
+		for y := 0; y < self.height; y++ {
+			ret.Halite[y * self.width + x] = self.halite[x][y]
+		}
+	}
+
+	for _, ship := range self.ships {
+
+		s := SnapshotShip{
+			Owner:		ship.Owner,
+			Sid:		ship.Sid,
+			X:			ship.X,
+			Y:			ship.Y,
+			Halite:		ship.Halite,
+			Score:		ship.Score,
+			Returning:	ship.Returning,
+			FinalDash:	ship.FinalDash,
+			Route:		ship.Route(),
+		}
+
+		if ship.target_ok {
+			target := ship.target
+			s.Target = &target
+		}
+
+		if ship.build_site_ok {
+			site := ship.build_site
+			s.BuildSite = &site
+		}
+
+		ret.Ships = append(ret.Ships, s)
+	}
+
+	for _, dropoff := range self.dropoffs {
+		ret.Dropoffs = append(ret.Dropoffs, SnapshotDropoff{dropoff.Owner, dropoff.X, dropoff.Y, dropoff.Factory})
+	}
+
+	return ret
+}
+
+func (self *Snapshot) Frame() (*Frame, error) {
+
+	if self.Version != SNAPSHOT_VERSION {
+		return nil, fmt.Errorf("snapshot version %d, but only version %d is supported", self.Version, SNAPSHOT_VERSION)
+	}
+
+	if self.Width <= 0 || self.Height <= 0 || len(self.Halite) != self.Width * self.Height {
+		return nil, fmt.Errorf("snapshot halite grid doesn't match its size %dx%d", self.Width, self.Height)
+	}
+
+	if len(self.Budgets) != self.Players || len(self.Dropoffs) < self.Players {
+		return nil, fmt.Errorf("snapshot doesn't have budgets and factories for %d players", self.Players)
+	}
+
+	if self.Pid < 0 || self.Pid >= self.Players || self.TruePid < 0 || self.TruePid >= self.Players {
+		return nil, fmt.Errorf("snapshot pid %d / true pid %d not valid for %d players", self.Pid, self.TruePid, self.Players)
+	}
+
+	in_bounds := func(x, y int) bool {
+		return x >= 0 && x < self.Width && y >= 0 && y < self.Height
+	}
+
+	for i, d := range self.Dropoffs {
+		if i < self.Players && (d.Factory == false || d.Owner != i) {
+			return nil, fmt.Errorf("snapshot dropoff %d should be player %d's factory", i, i)
+		}
+		if i >= self.Players && (d.Factory || d.Owner < 0 || d.Owner >= self.Players) {
+			return nil, fmt.Errorf("snapshot dropoff %d is a factory or has owner %d", i, d.Owner)
+		}
+		if in_bounds(d.X, d.Y) == false {
+			return nil, fmt.Errorf("snapshot dropoff %d is off the map at %d %d", i, d.X, d.Y)
+		}
+	}
+
+	frame := NewGame()
+
+	frame.Constants = self.Constants
+	frame.ParseTime = time.Now()
+	frame.players = self.Players
+	frame.width = self.Width
+	frame.height = self.Height
+	frame.pid = self.Pid
+	frame.__true_pid = self.TruePid
+	frame.turn = self.Turn
+	frame.highest_sid_seen = self.HighestSid
+	frame.initial_ground_halite = self.InitialGround
+
+	frame.Zerofy()
+
+	copy(frame.budgets, self.Budgets)
+
+	for x := 0; x < frame.width; x++ {
+		for y := 0; y < frame.height; y++ {
+			frame.halite[x][y] = self.Halite[y * frame.width + x]
+		}
+	}
+
+	for _, s := range self.Ships {
+
+		if s.Owner < 0 || s.Owner >= self.Players {
+			return nil, fmt.Errorf("snapshot ship %d has owner %d", s.Sid, s.Owner)
+		}
+
+		if in_bounds(s.X, s.Y) == false {
+			return nil, fmt.Errorf("snapshot ship %d is off the map at %d %d", s.Sid, s.X, s.Y)
+		}
+
+		for _, p := range s.points() {
+			if in_bounds(p.X, p.Y) == false {
+				return nil, fmt.Errorf("snapshot ship %d has a target, build site or route step off the map at %d %d", s.Sid, p.X, p.Y)
+			}
+		}
+
+		if frame.ship_xy_lookup[Point{s.X, s.Y}] != nil || frame.ship_id_lookup[s.Sid] != nil {
+			return nil, fmt.Errorf("snapshot ship %d shares its sid or cell with another", s.Sid)
+		}
+
+		ship := &Ship{
+			Frame:		frame,
+			Owner:		s.Owner,
+			Sid:		s.Sid,
+			X:			s.X,
+			Y:			s.Y,
+			Halite:		s.Halite,
+			Score:		s.Score,
+			Returning:	s.Returning,
+			FinalDash:	s.FinalDash,
+		}
+
+		if s.Target != nil {
+			ship.SetTarget(*s.Target)
+		}
+
+		if s.BuildSite != nil {
+			ship.SetBuildSite(*s.BuildSite)
+		}
+
+		if len(s.Route) > 0 {
+			ship.SetRoute(s.Route)
+		}
+
+		frame.ships = append(frame.ships, ship)
+		frame.ship_xy_lookup[Point{ship.X, ship.Y}] = ship
+		frame.ship_id_lookup[ship.Sid] = ship
+	}
+
+	for _, d := range self.Dropoffs {
+		frame.dropoffs = append(frame.dropoffs, &Dropoff{
+			Frame:		frame,
+			Factory:	d.Factory,
+			Owner:		d.Owner,
+			X:			d.X,
+			Y:			d.Y,
+		})
+	}
+
+	frame.FixInspiration()
+
+	return frame, nil
+}
+
+// ------------------------------------------------------------
+
+func (self *Frame) SaveSnapshot(filename string) error {
+
+	outfile, err := os.Create(filename)
+	if err != nil {
+		return err
+	}
+	defer outfile.Close()
+
+	if strings.HasSuffix(strings.ToLower(filename), ".gz") == false {
+		return json.NewEncoder(outfile).Encode(self.Snapshot())
+	}
+
+	zw := gzip.NewWriter(outfile)
+
+	err = json.NewEncoder(zw).Encode(self.Snapshot())
+	if err != nil {
+		return err
+	}
+
+	return zw.Close()
+}
+
+func LoadSnapshot(filename string) (*Frame, error) {
+
+	infile, err := os.Open(filename)
+	if err != nil {
+		return nil, err
+	}
+	defer infile.Close()
+
+	var r io.Reader = infile
+
+	if strings.HasSuffix(strings.ToLower(filename), ".gz") {
+		zr, err := gzip.NewReader(infile)
+		if err != nil {
+			return nil, err
+		}
+		defer zr.Close()
+		r = zr
+	}
+
+	snapshot := new(Snapshot)
+
+	err = json.NewDecoder(r).Decode(snapshot)
+	if err != nil {
+		return nil, err
+	}
+
+	return snapshot.Frame()
+}
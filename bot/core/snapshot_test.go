@@ -0,0 +1,159 @@
+package core
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+// testdata/snapshot-32-4p-120.json was saved by the bot (with -snapshot 120) in
+// a 4 player game, so its ships carry targets and routes.
+
+const SNAPSHOT_FIXTURE = "testdata/snapshot-32-4p-120.json"
+
+func TestSnapshotSaveLoad(t *testing.T) {
+
+	// Loading the fixture, saving it (plain and gzipped) and loading that must
+	// give back exactly the same snapshot and frame.
+
+	frame, err := LoadSnapshot(SNAPSHOT_FIXTURE)
+	if err != nil {
+		t.Fatalf("%v", err)
+	}
+
+	original := read_snapshot(t, SNAPSHOT_FIXTURE)
+
+	if reflect.DeepEqual(frame.Snapshot(), original) == false {
+		t.Errorf("loaded frame doesn't give back the fixture's snapshot")
+	}
+
+	dir, err := ioutil.TempDir("", "snapshot")
+	if err != nil {
+		t.Fatalf("%v", err)
+	}
+	defer os.RemoveAll(dir)
+
+	for _, name := range []string{"snapshot.json", "snapshot.json.gz"} {
+
+		filename := filepath.Join(dir, name)
+
+		err = frame.SaveSnapshot(filename)
+		if err != nil {
+			t.Fatalf("%s: %v", name, err)
+		}
+
+		loaded, err := LoadSnapshot(filename)
+		if err != nil {
+			t.Fatalf("%s: %v", name, err)
+		}
+
+		if loaded.Hash() != frame.Hash() {
+			t.Errorf("%s: hash %s, expected %s", name, loaded.Hash(), frame.Hash())
+		}
+
+		if reflect.DeepEqual(loaded.Snapshot(), original) == false {
+			t.Errorf("%s: snapshot changed by saving and loading", name)
+		}
+
+		for _, ship := range frame.ships {
+			other := loaded.Sid(ship.Sid)
+			if other == nil {
+				t.Errorf("%s: ship %d missing", name, ship.Sid)
+				continue
+			}
+			if other.target != ship.target || other.target_ok != ship.target_ok || other.Score != ship.Score ||
+					other.Returning != ship.Returning || reflect.DeepEqual(other.Route(), ship.Route()) == false {
+				t.Errorf("%s: ship %d AI state changed", name, ship.Sid)
+			}
+		}
+	}
+}
+
+func TestSnapshotOfParsedFrame(t *testing.T) {
+
+	// A snapshot of a parsed frame must remake it exactly, including the parts
+	// the snapshot doesn't store directly, e.g. inspiration.
+
+	tested := 0
+
+	play_back(t, "testdata/game-32-4p.txt", func(frame *Frame) {
+
+		if frame.Turn() % 25 != 0 {
+			return
+		}
+
+		remade, err := frame.Snapshot().Frame()
+		if err != nil {
+			t.Fatalf("turn %d: %v", frame.Turn(), err)
+		}
+
+		if remade.Hash() != frame.Hash() {
+			t.Errorf("turn %d: hash %s, expected %s", frame.Turn(), remade.Hash(), frame.Hash())
+		}
+
+		for _, ship := range frame.ships {
+			if remade.Sid(ship.Sid).Inspired != ship.Inspired {
+				t.Errorf("turn %d: ship %d inspiration differs", frame.Turn(), ship.Sid)
+			}
+		}
+
+		tested++
+	})
+
+	if tested == 0 {
+		t.Fatalf("no frames tested")
+	}
+}
+
+func TestSnapshotValidation(t *testing.T) {
+
+	breakers := map[string]func(s *Snapshot){
+		"version":				func(s *Snapshot) { s.Version++ },
+		"halite grid":			func(s *Snapshot) { s.Halite = s.Halite[1:] },
+		"budgets":				func(s *Snapshot) { s.Budgets = s.Budgets[1:] },
+		"pid":					func(s *Snapshot) { s.Pid = s.Players },
+		"negative pid":			func(s *Snapshot) { s.Pid = -1 },
+		"true pid":				func(s *Snapshot) { s.TruePid = s.Players },
+		"ship owner":			func(s *Snapshot) { s.Ships[0].Owner = s.Players },
+		"ship off map":			func(s *Snapshot) { s.Ships[0].X = s.Width },
+		"ship negative":		func(s *Snapshot) { s.Ships[0].Y = -1 },
+		"ship shared cell":		func(s *Snapshot) { s.Ships[1].X, s.Ships[1].Y = s.Ships[0].X, s.Ships[0].Y },
+		"target off map":		func(s *Snapshot) { s.Ships[0].Target = &Point{0, s.Height} },
+		"dropoff off map":		func(s *Snapshot) { s.Dropoffs[len(s.Dropoffs) - 1].X = -1 },
+		"factory order":		func(s *Snapshot) { s.Dropoffs[0], s.Dropoffs[1] = s.Dropoffs[1], s.Dropoffs[0] },
+		"factory missing":		func(s *Snapshot) { s.Dropoffs[0].Factory = false },
+		"late factory":			func(s *Snapshot) { s.Dropoffs[len(s.Dropoffs) - 1].Factory = true },
+	}
+
+	if _, err := read_snapshot(t, SNAPSHOT_FIXTURE).Frame(); err != nil {
+		t.Fatalf("fixture: %v", err)
+	}
+
+	for name, breaker := range breakers {
+		snapshot := read_snapshot(t, SNAPSHOT_FIXTURE)
+		breaker(snapshot)
+		if _, err := snapshot.Frame(); err == nil {
+			t.Errorf("%s: no error", name)
+		}
+	}
+}
+
+func read_snapshot(t *testing.T, filename string) *Snapshot {
+
+	b, err := ioutil.ReadFile(filename)
+	if err != nil {
+		t.Fatalf("%v", err)
+	}
+
+	ret := new(Snapshot)
+
+	err = json.Unmarshal(b, ret)
+	if err != nil {
+		t.Fatalf("%v", err)
+	}
+
+	return ret
+}
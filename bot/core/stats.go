@@ -0,0 +1,219 @@
+package core
+
+import (
+	"bufio"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+)
+
+// Per-turn statistics for every player, worked out by comparing consecutive
+// parsed frames, as with the Profiler. Quantities such as Spawned and Mined are
+// for the latest turn only; sum them for totals. Like the profiler, the keeper
+// is shared with simulated frames but only ever updated by Parse().
+
+type TurnStats struct {
+	Turn					int			`json:"turn"`
+	Pid						int			`json:"pid"`
+	Budget					int			`json:"budget"`
+	Ships					int			`json:"ships"`
+	Spawned					int			`json:"spawned"`
+	Lost					int			`json:"lost"`			// Not counting ships that became dropoffs
+	Carried					int			`json:"carried"`		// Total cargo of all ships
+	Mined					int			`json:"mined"`			// Cargo gained by ships that stayed still (includes inspiration bonus)
+	Burned					int			`json:"burned"`			// Halite spent on movement
+	Dropoffs				int			`json:"dropoffs"`		// Includes factory
+	GroundFraction			float64		`json:"ground_fraction"`
+	Collisions				int			`json:"collisions"`		// Ships lost to collisions, as guessed by the profiler
+	TurnMs					float64		`json:"turn_ms"`		// Our own turn time; 0 for other players
+}
+
+var STATS_COLUMNS = []string{"turn", "pid", "budget", "ships", "spawned", "lost", "carried", "mined", "burned", "dropoffs", "ground_fraction", "collisions", "turn_ms"}
+
+func (self *TurnStats) Row() []string {
+	return []string{
+		fmt.Sprintf("%d", self.Turn),
+		fmt.Sprintf("%d", self.Pid),
+		fmt.Sprintf("%d", self.Budget),
+		fmt.Sprintf("%d", self.Ships),
+		fmt.Sprintf("%d", self.Spawned),
+		fmt.Sprintf("%d", self.Lost),
+		fmt.Sprintf("%d", self.Carried),
+		fmt.Sprintf("%d", self.Mined),
+		fmt.Sprintf("%d", self.Burned),
+		fmt.Sprintf("%d", self.Dropoffs),
+		fmt.Sprintf("%.4f", self.GroundFraction),
+		fmt.Sprintf("%d", self.Collisions),
+		fmt.Sprintf("%.1f", self.TurnMs),
+	}
+}
+
+// ------------------------------------------------------------
+
+type StatsKeeper struct {
+	latest			map[int]*TurnStats
+	collisions		map[int]int			// Profiler's collision totals as of the last Observe()
+}
+
+func NewStatsKeeper() *StatsKeeper {
+	ret := new(StatsKeeper)
+	ret.latest = make(map[int]*TurnStats)
+	ret.collisions = make(map[int]int)
+	return ret
+}
+
+func (self *StatsKeeper) Observe(old_frame, new_frame *Frame) {
+
+	// Must be called after the profiler has seen the same frames, and after the
+	// maps are inherited so that GroundHalite() is the cheap, updated value.
+
+	self.latest = make(map[int]*TurnStats)
+
+	ground_fraction := 0.0
+	if new_frame.initial_ground_halite > 0 {
+		ground_fraction = float64(new_frame.GroundHalite()) / float64(new_frame.initial_ground_halite)
+	}
+
+	for pid := 0; pid < new_frame.players; pid++ {
+		self.latest[pid] = &TurnStats{
+			Turn: new_frame.turn,
+			Pid: pid,
+			Budget: new_frame.budgets[pid],
+			Ships: len(new_frame.Ships(pid)),
+			Dropoffs: len(new_frame.Dropoffs(pid)),
+			GroundFraction: ground_fraction,
+		}
+	}
+
+	for _, ship := range new_frame.ships {
+
+		stats := self.latest[ship.Owner]
+		stats.Carried += ship.Halite
+
+		old_ship := old_frame.ship_id_lookup[ship.Sid]
+
+		if old_ship == nil {
+			stats.Spawned++
+			continue
+		}
+
+		if old_ship.X == ship.X && old_ship.Y == ship.Y {
+			if ship.Halite > old_ship.Halite {
+				stats.Mined += ship.Halite - old_ship.Halite
+			}
+		} else {
+			mcr := new_frame.Constants.MOVE_COST_RATIO
+			if old_ship.Inspired { mcr = new_frame.Constants.INSPIRED_MOVE_COST_RATIO }
+			stats.Burned += old_frame.halite[old_ship.X][old_ship.Y] / mcr
+		}
+	}
+
+	for _, old_ship := range old_frame.ships {
+		if new_frame.ship_id_lookup[old_ship.Sid] != nil {
+			continue
+		}
+		if new_frame.PlayerCanDropoffAt(old_ship.Owner, old_ship) && old_frame.PlayerCanDropoffAt(old_ship.Owner, old_ship) == false {
+			continue
+		}
+		self.latest[old_ship.Owner].Lost++
+	}
+
+	if new_frame.profiler != nil {
+		for pid := 0; pid < new_frame.players; pid++ {
+			profile := new_frame.profiler.Profile(pid)
+			total := profile.SelfCollisions + profile.EnemyCollisions
+			self.latest[pid].Collisions = total - self.collisions[pid]
+			self.collisions[pid] = total
+		}
+	}
+}
+
+func (self *Frame) TurnStats(pid int) *TurnStats {		// Maybe nil. A copy, so the caller can fill in TurnMs.
+	if self.stats == nil || self.stats.latest[pid] == nil {
+		return nil
+	}
+	ret := *self.stats.latest[pid]
+	return &ret
+}
+
+// ------------------------------------------------------------
+// Writing the stats out, as CSV if the filename ends in .csv, or as JSON lines if
+// it ends in .jsonl. Like the trace, the file is created on first use; failure
+// just disables it.
+
+type StatsWriter struct {
+	outfile			*os.File
+	outfilename		string
+	closed			bool
+	csv				bool
+}
+
+func NewStatsWriter(outfilename string) (*StatsWriter, error) {
+
+	lower := strings.ToLower(outfilename)
+
+	if strings.HasSuffix(lower, ".csv") == false && strings.HasSuffix(lower, ".jsonl") == false {
+		return nil, fmt.Errorf("stats file %s should end in .csv or .jsonl", outfilename)
+	}
+
+	return &StatsWriter{outfilename: outfilename, csv: strings.HasSuffix(lower, ".csv")}, nil
+}
+
+func (self *StatsWriter) Write(frame *Frame, turn_time time.Duration) {
+
+	if self == nil || self.closed {
+		return
+	}
+
+	if self.outfile == nil {
+		var err error
+		self.outfile, err = os.Create(self.outfilename)
+		if err != nil {
+			self.closed = true
+			return
+		}
+		if self.csv {
+			w := csv.NewWriter(self.outfile)
+			w.Write(STATS_COLUMNS)
+			w.Flush()
+		}
+	}
+
+	w := bufio.NewWriter(self.outfile)
+	cw := csv.NewWriter(w)
+
+	for pid := 0; pid < frame.Players(); pid++ {
+
+		stats := frame.TurnStats(pid)
+		if stats == nil {
+			continue
+		}
+
+		if pid == frame.Pid() {
+			stats.TurnMs = float64(turn_time) / float64(time.Millisecond)
+		}
+
+		if self.csv {
+			cw.Write(stats.Row())
+		} else {
+			s, _ := json.Marshal(stats)
+			w.Write(append(s, '\n'))
+		}
+	}
+
+	cw.Flush()
+	w.Flush()
+}
+
+func (self *StatsWriter) Close() {
+	if self == nil || self.closed {
+		return
+	}
+	self.closed = true
+	if self.outfile != nil {
+		self.outfile.Close()
+	}
+}
@@ -0,0 +1,97 @@
+package core
+
+import (
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+)
+
+// Optional timing of the phases of a turn and of map construction. Once
+// StartTimings() is called, each Time() ... done() pair adds one sample to its
+// phase; LogTimings() writes per-phase percentiles, normally at game end.
+//
+// When timing is off, Time() returns a do-nothing function and costs nothing else.
+//
+//		done := hal.Time("SetTargets")
+//		SetTargets(...)
+//		done()
+//
+// Maps are timed as "map: <name>" when made for a parsed frame, and as
+// "sim map: <name>" when made for a Remake() or SimGen() frame (e.g. by the
+// lookahead), so the two don't muddle each other's percentiles.
+
+type Timings struct {
+	mutex			sync.Mutex					// Maps may be made from several goroutines
+	samples			map[string][]time.Duration
+	order			[]string					// Phases in order of first appearance
+}
+
+var timings *Timings
+
+func StartTimings() {
+	timings = &Timings{samples: make(map[string][]time.Duration)}
+}
+
+func no_op() {}
+
+func Time(phase string) func() {
+
+	if timings == nil {
+		return no_op
+	}
+
+	start := time.Now()
+
+	return func() {
+		timings.add(phase, time.Now().Sub(start))
+	}
+}
+
+func (self *Frame) time_map(name string) func() {
+	if self.simulated {
+		return Time("sim map: " + name)
+	}
+	return Time("map: " + name)
+}
+
+func (self *Timings) add(phase string, d time.Duration) {
+	self.mutex.Lock()
+	defer self.mutex.Unlock()
+	if self.samples[phase] == nil {
+		self.order = append(self.order, phase)
+	}
+	self.samples[phase] = append(self.samples[phase], d)
+}
+
+func percentile(sorted []time.Duration, p float64) time.Duration {
+	return sorted[int(p * float64(len(sorted) - 1) + 0.5)]
+}
+
+func (self *Frame) LogTimings() {
+
+	if timings == nil {
+		return
+	}
+
+	timings.mutex.Lock()
+	defer timings.mutex.Unlock()
+
+	logger := self.Logger("timings")
+
+	for _, phase := range timings.order {
+
+		sorted := append([]time.Duration(nil), timings.samples[phase]...)
+		sort.Slice(sorted, func(a, b int) bool {
+			return sorted[a] < sorted[b]
+		})
+
+		var total time.Duration
+		for _, d := range sorted {
+			total += d
+		}
+
+		logger.Info(fmt.Sprintf("%-22s n %5d   total %-12v p50 %-12v p90 %-12v p99 %-12v max %v",
+			phase, len(sorted), total, percentile(sorted, 0.5), percentile(sorted, 0.9), percentile(sorted, 0.99), sorted[len(sorted) - 1]))
+	}
+}
@@ -71,3 +71,11 @@ func Make2dBoolArray(width, height int) [][]bool {
 	}
 	return ret
 }
+
+func Make2dFloatArray(width, height int) [][]float64 {
+	ret := make([][]float64, width)
+	for x := 0; x < width; x++ {
+		ret[x] = make([]float64, height)
+	}
+	return ret
+}
@@ -0,0 +1,78 @@
+package logging
+
+import (
+	"fmt"
+)
+
+const FLOG_VERSION = 3
+
+// A layer is a named overlay the viewer can switch on and off. Layers with
+// numeric values have a scale: values from Min to Max are coloured from Low
+// to High (both "#rrggbb"), and values outside are clamped. The colour is
+// also written into each object, for viewers that know nothing of layers.
+//
+// Layers must be defined before the first flog object is written, since the
+// index goes in the file header; package init() functions are a good place.
+
+type FlogLayer struct {
+	Name			string		`json:"name"`
+	Description		string		`json:"description,omitempty"`
+	Min				float64		`json:"min"`
+	Max				float64		`json:"max"`
+	Low				string		`json:"low,omitempty"`
+	High			string		`json:"high,omitempty"`
+}
+
+var flog_layers []*FlogLayer
+
+func DefineFlogLayer(name, description string, min, max float64, low, high string) {
+
+	for _, layer := range flog_layers {
+		if layer.Name == name {
+			*layer = FlogLayer{name, description, min, max, low, high}
+			return
+		}
+	}
+
+	flog_layers = append(flog_layers, &FlogLayer{name, description, min, max, low, high})
+}
+
+func FlogLayerByName(name string) *FlogLayer {		// Maybe nil
+	for _, layer := range flog_layers {
+		if layer.Name == name {
+			return layer
+		}
+	}
+	return nil
+}
+
+func LayerColour(name string, value float64) string {
+
+	// "" if the layer is unknown or has no scale.
+
+	layer := FlogLayerByName(name)
+
+	if layer == nil || layer.Low == "" || layer.High == "" || layer.Max == layer.Min {
+		return ""
+	}
+
+	f := (value - layer.Min) / (layer.Max - layer.Min)
+
+	if f < 0 { f = 0 }
+	if f > 1 { f = 1 }
+
+	lr, lg, lb := parse_colour(layer.Low)
+	hr, hg, hb := parse_colour(layer.High)
+
+	mix := func(a, b int) int {
+		return a + int(float64(b - a) * f + 0.5)
+	}
+
+	return fmt.Sprintf("#%02x%02x%02x", mix(lr, hr), mix(lg, hg), mix(lb, hb))
+}
+
+func parse_colour(s string) (int, int, int) {
+	var r, g, b int
+	fmt.Sscanf(s, "#%02x%02x%02x", &r, &g, &b)
+	return r, g, b
+}
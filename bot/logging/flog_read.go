@@ -0,0 +1,165 @@
+package logging
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+)
+
+func ReadFlog(r io.Reader) (*FlogHeader, []*FlogObject, error) {
+
+	// Reads a flog in either format: JSON lines (with or without the header),
+	// or the old JSON array. Truncation is tolerated: a partial last line, or
+	// an array that stops part way, just ends the objects. The header is nil
+	// if there wasn't one.
+
+	br := bufio.NewReader(r)
+
+	first, err := first_non_space(br)
+	if err == io.EOF {
+		return nil, nil, nil
+	}
+	if err != nil {
+		return nil, nil, err
+	}
+
+	if first == '[' {
+		return read_flog_array(br)
+	}
+
+	return read_flog_lines(br)
+}
+
+func first_non_space(br *bufio.Reader) (byte, error) {
+	for {
+		b, err := br.Peek(1)
+		if err != nil {
+			return 0, err
+		}
+		if b[0] != ' ' && b[0] != '\t' && b[0] != '\r' && b[0] != '\n' {
+			return b[0], nil
+		}
+		br.ReadByte()
+	}
+}
+
+func read_flog_lines(br *bufio.Reader) (*FlogHeader, []*FlogObject, error) {
+
+	var header *FlogHeader
+	var objects []*FlogObject
+
+	for n := 1; ; n++ {
+
+		line, err := br.ReadBytes('\n')
+		complete := err == nil
+
+		if err != nil && err != io.EOF {
+			return header, objects, err
+		}
+
+		line = bytes.TrimSpace(line)
+
+		if len(line) > 0 {
+
+			var raw map[string]json.RawMessage
+			perr := json.Unmarshal(line, &raw)
+
+			if perr == nil && raw["layers"] != nil {
+				h := new(FlogHeader)
+				perr = json.Unmarshal(line, h)
+				if perr == nil && header == nil {
+					header = h
+				}
+			} else if perr == nil {
+				f := new(FlogObject)
+				perr = json.Unmarshal(line, f)
+				if perr == nil {
+					objects = append(objects, f)
+				}
+			}
+
+			if perr != nil {
+				if complete {
+					return header, objects, fmt.Errorf("ReadFlog: line %d: %v", n, perr)
+				}
+				break					// Partial last line
+			}
+		}
+
+		if complete == false {
+			break
+		}
+	}
+
+	return header, objects, nil
+}
+
+func read_flog_array(br *bufio.Reader) (*FlogHeader, []*FlogObject, error) {
+
+	var header *FlogHeader
+	var objects []*FlogObject
+
+	dec := json.NewDecoder(br)
+
+	if _, err := dec.Token(); err != nil {				// The '['
+		return nil, nil, err
+	}
+
+	for dec.More() {
+
+		var raw json.RawMessage
+
+		if dec.Decode(&raw) != nil {
+			break						// Truncated
+		}
+
+		if bytes.Contains(raw, []byte(`"layers"`)) && header == nil {
+			h := new(FlogHeader)
+			if json.Unmarshal(raw, h) == nil && h.Layers != nil {
+				header = h
+				continue
+			}
+		}
+
+		f := new(FlogObject)
+		if err := json.Unmarshal(raw, f); err != nil {
+			return header, objects, err
+		}
+		objects = append(objects, f)
+	}
+
+	return header, objects, nil
+}
+
+func WriteFlogArray(w io.Writer, objects []*FlogObject) error {
+
+	// The old format: one JSON array of objects. There's no header, since the
+	// readers of this format know nothing of layers.
+
+	bw := bufio.NewWriter(w)
+
+	bw.WriteString("[")
+
+	first := true
+
+	write := func(v interface{}) {
+		s, _ := json.Marshal(v)
+		if first {
+			bw.WriteString("\n  ")
+			first = false
+		} else {
+			bw.WriteString(",\n  ")
+		}
+		bw.Write(s)
+	}
+
+	for _, f := range objects {
+		write(f)
+	}
+
+	bw.WriteString("\n]")
+
+	return bw.Flush()
+}
@@ -0,0 +1,184 @@
+package logging
+
+import (
+	"bytes"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func test_flog_objects() []*FlogObject {
+	value := 0.25
+	tx, ty := 4, 5
+	return []*FlogObject{
+		&FlogObject{T: 0, X: 1, Y: 2, Msg: "hello", Colour: "#ff0000"},
+		&FlogObject{T: 0, X: 3, Y: 4, Layer: "test", Value: &value, Colour: LayerColour("test", value)},
+		&FlogObject{T: 1, X: 5, Y: 6, Layer: "test", ToX: &tx, ToY: &ty},
+		&FlogObject{T: 2, X: 7, Y: 8, Msg: "last"},
+	}
+}
+
+func write_test_flog(t *testing.T) []byte {
+
+	// A flog as the bot writes it: the header line, then one object per line.
+
+	DefineFlogLayer("test", "A layer for testing", 0, 1, "#000000", "#ffffff")
+
+	dir, err := ioutil.TempDir("", "flog")
+	if err != nil {
+		t.Fatalf("%v", err)
+	}
+	defer os.RemoveAll(dir)
+
+	filename := filepath.Join(dir, "flog.jsonl")
+
+	flog := NewFlog(filename)
+	for _, f := range test_flog_objects() {
+		flog.Write(f)
+	}
+	flog.Close()
+
+	b, err := ioutil.ReadFile(filename)
+	if err != nil {
+		t.Fatalf("%v", err)
+	}
+
+	return b
+}
+
+func TestReadFlogHeader(t *testing.T) {
+
+	header, objects, err := ReadFlog(bytes.NewReader(write_test_flog(t)))
+	if err != nil {
+		t.Fatalf("%v", err)
+	}
+
+	if header == nil {
+		t.Fatalf("no header")
+	}
+
+	if header.T != -1 || header.Version != FLOG_VERSION {
+		t.Errorf("header t %d, version %d", header.T, header.Version)
+	}
+
+	if reflect.DeepEqual(header.Layers, flog_layers) == false {
+		t.Errorf("header layers %v, expected %v", header.Layers, flog_layers)
+	}
+
+	if reflect.DeepEqual(objects, test_flog_objects()) == false {
+		t.Errorf("objects changed by writing and reading")
+	}
+
+	// Without the header, the objects are the same and the header is nil...
+
+	b := write_test_flog(t)
+	b = b[bytes.IndexByte(b, '\n') + 1:]
+
+	header, objects, err = ReadFlog(bytes.NewReader(b))
+	if err != nil {
+		t.Fatalf("%v", err)
+	}
+
+	if header != nil {
+		t.Errorf("header found in a headerless flog")
+	}
+
+	if reflect.DeepEqual(objects, test_flog_objects()) == false {
+		t.Errorf("objects changed by writing and reading without a header")
+	}
+}
+
+func TestReadFlogTruncated(t *testing.T) {
+
+	// The bot can be killed part way through a line. Everything before the
+	// partial record must still be read, with no error.
+
+	b := write_test_flog(t)
+	b = b[:len(b) - 8]
+
+	header, objects, err := ReadFlog(bytes.NewReader(b))
+	if err != nil {
+		t.Fatalf("%v", err)
+	}
+
+	expected := test_flog_objects()
+	expected = expected[:len(expected) - 1]
+
+	if header == nil || reflect.DeepEqual(objects, expected) == false {
+		t.Errorf("got %d objects (header %v), expected %d", len(objects), header != nil, len(expected))
+	}
+
+	// But a bad line that isn't the last is an error...
+
+	lines := strings.Split(string(write_test_flog(t)), "\n")
+	lines[2] = lines[2][:len(lines[2]) - 8]
+
+	if _, _, err := ReadFlog(strings.NewReader(strings.Join(lines, "\n"))); err == nil {
+		t.Errorf("no error for a bad line in the middle")
+	}
+}
+
+func TestReadFlogArray(t *testing.T) {
+
+	// The legacy format, exactly as older versions wrote it...
+
+	legacy := "[\n  " + `{"t":0,"x":1,"y":2,"msg":"hello","colour":"#ff0000"}` + ",\n  " + `{"t":2,"x":7,"y":8,"msg":"last"}` + "\n]"
+
+	expected := []*FlogObject{
+		&FlogObject{T: 0, X: 1, Y: 2, Msg: "hello", Colour: "#ff0000"},
+		&FlogObject{T: 2, X: 7, Y: 8, Msg: "last"},
+	}
+
+	header, objects, err := ReadFlog(strings.NewReader(legacy))
+	if err != nil {
+		t.Fatalf("%v", err)
+	}
+
+	if header != nil || reflect.DeepEqual(objects, expected) == false {
+		t.Errorf("legacy array read wrongly")
+	}
+
+	// Those versions only closed the array on exit, so a killed bot left it open...
+
+	_, objects, err = ReadFlog(strings.NewReader(strings.TrimSuffix(legacy, "\n]")))
+	if err != nil {
+		t.Fatalf("%v", err)
+	}
+
+	if reflect.DeepEqual(objects, expected) == false {
+		t.Errorf("unclosed legacy array read wrongly")
+	}
+
+	// WriteFlogArray() writes it, and a truncated one still gives what's complete...
+
+	var buf bytes.Buffer
+
+	err = WriteFlogArray(&buf, test_flog_objects())
+	if err != nil {
+		t.Fatalf("%v", err)
+	}
+
+	_, objects, err = ReadFlog(bytes.NewReader(buf.Bytes()))
+	if err != nil {
+		t.Fatalf("%v", err)
+	}
+
+	if reflect.DeepEqual(objects, test_flog_objects()) == false {
+		t.Errorf("objects changed by WriteFlogArray() and reading")
+	}
+
+	b := buf.Bytes()
+	b = b[:len(b) - 10]
+
+	_, objects, err = ReadFlog(bytes.NewReader(b))
+	if err != nil {
+		t.Fatalf("%v", err)
+	}
+
+	if len(objects) != len(test_flog_objects()) - 1 {
+		t.Errorf("truncated array gave %d objects, expected %d", len(objects), len(test_flog_objects()) - 1)
+	}
+}
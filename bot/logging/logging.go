@@ -1,6 +1,7 @@
 package logging
 
 import (
+	"bufio"
 	"encoding/json"
 	"fmt"
 	"os"
@@ -28,12 +29,8 @@ func StopLog() {
 	log.Close()
 }
 
-func Log(format_string string, args ...interface{}) {
-	log.Log(format_string, args...)
-}
-
-func LogOnce(format_string string, args ...interface{}) bool {
-	return log.LogOnce(format_string, args...)
+func Log(format_string string, args ...interface{}) {		// An INFO record with no turn, pid, ship or subsystem
+	Emit(&Record{Level: INFO, Turn: -1, Pid: -1, Sid: -1, Msg: fmt.Sprintf(format_string, args...)})
 }
 
 func StartFlog(outfilename string) {
@@ -49,12 +46,25 @@ func Flog(t, x, y int, msg, colour string) {
 	flog.Flog(t, x, y, msg, colour)
 }
 
+func FlogIn(t, x, y int, layer, msg, colour string) {
+	flog.Write(&FlogObject{T: t, X: x, Y: y, Layer: layer, Msg: msg, Colour: colour})
+}
+
+func FlogValue(t, x, y int, layer string, value float64) {
+	flog.Write(&FlogObject{T: t, X: x, Y: y, Layer: layer, Value: &value, Colour: LayerColour(layer, value)})
+}
+
+func FlogArrow(t, x, y, tx, ty int, layer, colour string) {
+	flog.Write(&FlogObject{T: t, X: x, Y: y, Layer: layer, ToX: &tx, ToY: &ty, Colour: colour})
+}
+
 // --------------------------------------------------------------------
+// A Logfile is the text sink for records from Emit(); everything written to it
+// goes through there, so levels and silenced subsystems always apply.
 
 type Logfile struct {
 	outfile			*os.File
 	outfilename		string
-	logged_once		map[string]bool
 	closed			bool
 }
 
@@ -62,12 +72,15 @@ func NewLog(outfilename string) *Logfile {
 	return &Logfile{
 		nil,
 		outfilename,
-		make(map[string]bool),
 		false,
 	}
 }
 
-func (self *Logfile) Log(format_string string, args ...interface{}) {
+func (self *Logfile) Write(r *Record) {
+	self.write_line(r.Text())
+}
+
+func (self *Logfile) write_line(s string) {
 
 	if self == nil || self.closed || globally_suppressed {
 		return
@@ -91,22 +104,7 @@ func (self *Logfile) Log(format_string string, args ...interface{}) {
 		}
 	}
 
-	fmt.Fprintf(self.outfile, format_string + "\r\n", args...)
-}
-
-func (self *Logfile) LogOnce(format_string string, args ...interface{}) bool {
-
-	if self == nil || self.closed || globally_suppressed {
-		return false
-	}
-
-	if self.logged_once[format_string] == false {
-		self.logged_once[format_string] = true         // Note that it's format_string that is checked / saved
-		self.Log(format_string, args...)
-		return true
-	}
-
-	return false
+	fmt.Fprint(self.outfile, s + "\r\n")
 }
 
 func (self *Logfile) Close() {
@@ -118,13 +116,25 @@ func (self *Logfile) Close() {
 }
 
 // ---------------------------------------------------------------
-// This is a simple logger that I use for saving a JSON array of
-// objects for later interpretation by Fluorine.
+// This is a simple logger that I use for saving objects for later
+// interpretation by Fluorine. The file is JSON lines: the first line
+// is a header (with t of -1) listing the layers defined with
+// DefineFlogLayer(), then one object per line. Objects may belong to
+// a layer, carry a numeric value, or be an arrow to (tx, ty).
+//
+// Writes are buffered. The buffer is flushed when the first object of
+// a new turn is written (and on Close), so if the bot is killed the
+// file has every turn before the last one that flogged anything, plus
+// maybe some of that one ending in a partial line, which ReadFlog()
+// ignores. WriteFlogArray() converts to the old format (a single JSON
+// array).
 
 type Flogfile struct {
 	outfile			*os.File
+	writer			*bufio.Writer
 	outfilename		string
 	at_start		bool
+	last_t			int
 	closed			bool
 }
 
@@ -134,13 +144,25 @@ type FlogObject struct {
 	Y				int			`json:"y"`
 	Msg				string		`json:"msg,omitempty"`
 	Colour			string		`json:"colour,omitempty"`
+	Layer			string		`json:"layer,omitempty"`
+	Value			*float64	`json:"value,omitempty"`
+	ToX				*int		`json:"tx,omitempty"`
+	ToY				*int		`json:"ty,omitempty"`
+}
+
+type FlogHeader struct {
+	T				int			`json:"t"`			// Always -1
+	Version			int			`json:"version"`
+	Layers			[]*FlogLayer	`json:"layers"`
 }
 
 func NewFlog(outfilename string) *Flogfile {
 	return &Flogfile{
+		nil,
 		nil,
 		outfilename,
 		true,
+		-1,
 		false,
 	}
 }
@@ -149,6 +171,11 @@ func (self *Flogfile) Flog(t, x, y int, msg, colour string) {
 
 	// msg or colour can be ""
 
+	self.Write(&FlogObject{T: t, X: x, Y: y, Msg: msg, Colour: colour})
+}
+
+func (self *Flogfile) Write(f *FlogObject) {
+
 	if self == nil || self.closed || globally_suppressed {
 		return
 	}
@@ -160,27 +187,32 @@ func (self *Flogfile) Flog(t, x, y int, msg, colour string) {
 			self.closed = true
 			return
 		}
+		self.writer = bufio.NewWriter(self.outfile)
 	}
 
-	f := FlogObject{T: t, X: x, Y: y, Msg: msg, Colour: colour}
-
-	s, _ := json.Marshal(f)
-
 	if self.at_start {
-		fmt.Fprintf(self.outfile, "[\n  ")
+		header, _ := json.Marshal(FlogHeader{T: -1, Version: FLOG_VERSION, Layers: flog_layers})
+		self.writer.Write(append(header, '\n'))
 		self.at_start = false
-	} else {
-		fmt.Fprintf(self.outfile, ",\n  ")
 	}
 
-	fmt.Fprintf(self.outfile, string(s))
+	if f.T != self.last_t {
+		self.writer.Flush()
+		self.last_t = f.T
+	}
+
+	s, _ := json.Marshal(f)
+
+	self.writer.Write(append(s, '\n'))
 }
 
 func (self *Flogfile) Close() {
 	if self == nil || self.closed {
 		return
 	}
-	fmt.Fprintf(self.outfile, "\n]")
 	self.closed = true
-	self.outfile.Close()
+	if self.outfile != nil {
+		self.writer.Flush()
+		self.outfile.Close()
+	}
 }
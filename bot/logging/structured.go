@@ -0,0 +1,299 @@
+package logging
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"strings"
+)
+
+// --------------------------------------------------------------------
+// Structured logging. Every line is a Record with a level, the turn, the
+// player and ship it concerns (if any), a subsystem, a message, and some
+// key/value fields. Records below the minimum level, or from a silenced
+// subsystem, go nowhere. The rest go to the text log (in the old format,
+// so plain Log() lines look as they always did) and to any extra sinks,
+// such as a JSON-lines file.
+
+type Level int
+
+const (
+	DEBUG Level = iota
+	INFO
+	WARN
+	ERROR
+)
+
+var level_names = []string{"debug", "info", "warn", "error"}
+
+func (self Level) String() string {
+	if self < DEBUG || self > ERROR {
+		return fmt.Sprintf("level%d", int(self))
+	}
+	return level_names[self]
+}
+
+func ParseLevel(s string) (Level, bool) {
+	for n, name := range level_names {
+		if strings.ToLower(s) == name {
+			return Level(n), true
+		}
+	}
+	return INFO, false
+}
+
+type Field struct {
+	Key				string
+	Value			interface{}
+}
+
+type Record struct {
+	Level			Level
+	Turn			int			// -1 if none
+	Pid				int			// -1 if none
+	Sid				int			// -1 if none
+	Subsystem		string		// "" if none
+	Msg				string
+	Fields			[]Field
+}
+
+type Sink interface {
+	Write(r *Record)
+	Close()
+}
+
+var min_level = INFO
+var silenced = make(map[string]bool)
+var sinks []Sink
+var seen_once = make(map[string]bool)
+
+func SetLevel(level Level) { min_level = level }
+func Silence(subsystem string) { silenced[subsystem] = true }
+func Unsilence(subsystem string) { delete(silenced, subsystem) }
+
+func Enabled(level Level, subsystem string) bool {
+	return globally_suppressed == false && level >= min_level && silenced[subsystem] == false
+}
+
+func Once(key string) bool {		// True only the first time <key> is seen
+	if seen_once[key] {
+		return false
+	}
+	seen_once[key] = true
+	return true
+}
+
+func AddSink(sink Sink) {
+	sinks = append(sinks, sink)
+}
+
+func StopSinks() {
+	for _, sink := range sinks {
+		sink.Close()
+	}
+	sinks = nil
+}
+
+func StartJSONLog(outfilename string) {
+	AddSink(NewJSONLog(outfilename))
+}
+
+func Emit(r *Record) {
+
+	if Enabled(r.Level, r.Subsystem) == false {
+		return
+	}
+
+	log.Write(r)
+
+	for _, sink := range sinks {
+		sink.Write(r)
+	}
+}
+
+// --------------------------------------------------------------------
+// A Context holds the parts of a Record that stay the same across many
+// log calls, e.g. core.Frame.Logger() fills in the turn and pid.
+
+type Context struct {
+	Turn			int
+	Pid				int
+	Sid				int
+	Subsystem		string
+}
+
+func NewContext(subsystem string) Context {
+	return Context{Turn: -1, Pid: -1, Sid: -1, Subsystem: subsystem}
+}
+
+func (self Context) Ship(sid int) Context {
+	self.Sid = sid
+	return self
+}
+
+func (self Context) Debug(msg string, kv ...interface{}) { self.emit(DEBUG, msg, kv) }
+func (self Context) Info(msg string, kv ...interface{}) { self.emit(INFO, msg, kv) }
+func (self Context) Warn(msg string, kv ...interface{}) { self.emit(WARN, msg, kv) }
+func (self Context) Error(msg string, kv ...interface{}) { self.emit(ERROR, msg, kv) }
+
+func (self Context) InfoOnce(msg string, kv ...interface{}) bool { return self.emit_once(INFO, msg, kv) }
+func (self Context) WarnOnce(msg string, kv ...interface{}) bool { return self.emit_once(WARN, msg, kv) }
+
+func (self Context) emit(level Level, msg string, kv []interface{}) {
+
+	if Enabled(level, self.Subsystem) == false {			// Save building the fields
+		return
+	}
+
+	Emit(&Record{
+		Level: level,
+		Turn: self.Turn,
+		Pid: self.Pid,
+		Sid: self.Sid,
+		Subsystem: self.Subsystem,
+		Msg: msg,
+		Fields: make_fields(kv),
+	})
+}
+
+func (self Context) emit_once(level Level, msg string, kv []interface{}) bool {
+
+	// Like Frame.LogOnce(), it's the subsystem and message that are checked, not the fields.
+
+	if Enabled(level, self.Subsystem) == false || Once(self.Subsystem + "\x00" + msg) == false {
+		return false
+	}
+
+	self.emit(level, msg, kv)
+	return true
+}
+
+func make_fields(kv []interface{}) []Field {
+
+	// Alternating keys and values. A missing final value is logged as such.
+
+	var ret []Field
+
+	for n := 0; n < len(kv); n += 2 {
+		key := fmt.Sprintf("%v", kv[n])
+		if n + 1 < len(kv) {
+			ret = append(ret, Field{key, kv[n + 1]})
+		} else {
+			ret = append(ret, Field{key, "(missing)"})
+		}
+	}
+
+	return ret
+}
+
+// --------------------------------------------------------------------
+
+func (self *Record) Text() string {
+
+	// e.g. "t  57: warn: [resolver] ship 12: cancelling move move=e"
+
+	var b strings.Builder
+
+	if self.Turn >= 0 {
+		fmt.Fprintf(&b, "t %3d: ", self.Turn)
+	}
+	if self.Level != INFO {
+		fmt.Fprintf(&b, "%v: ", self.Level)
+	}
+	if self.Subsystem != "" {
+		fmt.Fprintf(&b, "[%s] ", self.Subsystem)
+	}
+	if self.Sid >= 0 {
+		fmt.Fprintf(&b, "ship %d: ", self.Sid)
+	}
+
+	b.WriteString(self.Msg)
+
+	for _, field := range self.Fields {
+		fmt.Fprintf(&b, " %s=%v", field.Key, field.Value)
+	}
+
+	return b.String()
+}
+
+type json_record struct {
+	Level			string					`json:"level"`
+	Turn			*int					`json:"turn,omitempty"`
+	Pid				*int					`json:"pid,omitempty"`
+	Sid				*int					`json:"sid,omitempty"`
+	Subsystem		string					`json:"sub,omitempty"`
+	Msg				string					`json:"msg"`
+	Fields			map[string]interface{}	`json:"fields,omitempty"`
+}
+
+func (self *Record) JSON() []byte {
+
+	j := json_record{Level: self.Level.String(), Subsystem: self.Subsystem, Msg: self.Msg}
+
+	if self.Turn >= 0 { j.Turn = &self.Turn }
+	if self.Pid >= 0 { j.Pid = &self.Pid }
+	if self.Sid >= 0 { j.Sid = &self.Sid }
+
+	if len(self.Fields) > 0 {
+		j.Fields = make(map[string]interface{})
+		for _, field := range self.Fields {
+			j.Fields[field.Key] = field.Value
+		}
+	}
+
+	s, err := json.Marshal(j)
+
+	if err != nil {				// Some value couldn't be marshalled; fall back to strings
+		for key, val := range j.Fields {
+			j.Fields[key] = fmt.Sprintf("%v", val)
+		}
+		s, _ = json.Marshal(j)
+	}
+
+	return s
+}
+
+// --------------------------------------------------------------------
+// A sink writing one JSON object per line.
+
+type JSONLog struct {
+	outfile			*os.File
+	outfilename		string
+	closed			bool
+}
+
+func NewJSONLog(outfilename string) *JSONLog {
+	return &JSONLog{
+		nil,
+		outfilename,
+		false,
+	}
+}
+
+func (self *JSONLog) Write(r *Record) {
+
+	if self == nil || self.closed {
+		return
+	}
+
+	if self.outfile == nil {
+		var err error
+		self.outfile, err = os.Create(self.outfilename)
+		if err != nil {
+			self.closed = true
+			return
+		}
+	}
+
+	self.outfile.Write(append(r.JSON(), '\n'))
+}
+
+func (self *JSONLog) Close() {
+	if self == nil || self.closed {
+		return
+	}
+	self.closed = true
+	if self.outfile != nil {
+		self.outfile.Close()
+	}
+}
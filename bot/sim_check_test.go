@@ -0,0 +1,74 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"testing"
+
+	"./logging"
+	hal "./core"
+)
+
+func TestSimReplayHasLastTurn(t *testing.T) {
+
+	// The recorded replay must cover every turn, including the last one, whose
+	// moves are the end of the final dash.
+
+	infile, err := os.Open("core/testdata/game-32-4p.txt")
+	if err != nil {
+		t.Fatalf("%v", err)
+	}
+	defer infile.Close()
+
+	hal.SetInput(infile)
+	defer hal.SetInput(os.Stdin)
+
+	frame := hal.NewGame()
+	frame.PrePreParse()
+	frame.PreParse()
+
+	frame.Constants.MAX_TURNS = 100			// A short game, so ships are still out in the final dash
+
+	replay := hal.NewReplay(frame, "test")
+
+	logging.Suppress()
+	sim_check(frame, replay)
+	logging.Allow()
+
+	max_turns := frame.Constants.MAX_TURNS
+
+	if replay.Write(os.DevNull) != nil {		// Fills in the statistics
+		t.Fatalf("couldn't finish replay")
+	}
+
+	if replay.GameStatistics.NumberTurns != max_turns {
+		t.Errorf("number_turns %d, expected %d", replay.GameStatistics.NumberTurns, max_turns)
+	}
+
+	if len(replay.FullFrames) != max_turns + 1 {
+		t.Fatalf("%d full frames, expected %d", len(replay.FullFrames), max_turns + 1)
+	}
+
+	last := replay.FullFrames[max_turns]
+	previous := replay.FullFrames[max_turns - 1]
+
+	deposited := false
+
+	for pid := 0; pid < frame.Players(); pid++ {
+
+		key := fmt.Sprintf("%d", pid)
+
+		if last.Deposited[key] > previous.Deposited[key] {
+			deposited = true
+		}
+
+		if replay.GameStatistics.PlayerStatistics[pid].FinalProduction != last.Energy[key] {
+			t.Errorf("pid %d: final_production %d, but the last frame has energy %d",
+				pid, replay.GameStatistics.PlayerStatistics[pid].FinalProduction, last.Energy[key])
+		}
+	}
+
+	if deposited == false {
+		t.Errorf("no deposits on the last turn")
+	}
+}
@@ -0,0 +1,53 @@
+package main
+
+// Converts a flog (JSON lines, possibly truncated) to the old single-array
+// format that older versions of Fluorine expect.
+//
+//		flogconv flogs/flog-123-0.jsonl flog-123-0.json
+
+import (
+	"fmt"
+	"os"
+
+	"../../logging"
+)
+
+func main() {
+
+	if len(os.Args) < 3 {
+		fmt.Printf("Usage: %s <infile> <outfile>\n", os.Args[0])
+		os.Exit(1)
+	}
+
+	count, err := convert(os.Args[1], os.Args[2])
+	if err != nil {
+		fmt.Printf("%v\n", err)
+		os.Exit(1)
+	}
+
+	fmt.Printf("Wrote %d objects\n", count)
+}
+
+func convert(infilename, outfilename string) (int, error) {
+
+	// Returns how many objects were written. The outfile isn't made unless the infile could be read.
+
+	infile, err := os.Open(infilename)
+	if err != nil {
+		return 0, err
+	}
+	defer infile.Close()
+
+	_, objects, err := logging.ReadFlog(infile)
+	if err != nil {
+		return 0, err
+	}
+
+	outfile, err := os.Create(outfilename)
+	if err != nil {
+		return 0, err
+	}
+	defer outfile.Close()
+
+	return len(objects), logging.WriteFlogArray(outfile, objects)
+}
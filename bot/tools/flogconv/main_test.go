@@ -0,0 +1,102 @@
+package main
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"../../logging"
+)
+
+func TestConvert(t *testing.T) {
+
+	// Whatever the input format, the output is a complete JSON array of just
+	// the objects: no header, nothing from a partial last record.
+
+	header := `{"t":-1,"version":3,"layers":[{"name":"test","min":0,"max":1}]}`
+	first := `{"t":0,"x":1,"y":2,"msg":"hello","colour":"#ff0000"}`
+	second := `{"t":1,"x":3,"y":4,"layer":"test","value":0.5}`
+
+	expected := []logging.FlogObject{
+		{T: 0, X: 1, Y: 2, Msg: "hello", Colour: "#ff0000"},
+		{T: 1, X: 3, Y: 4, Layer: "test", Value: new(float64)},
+	}
+	*expected[1].Value = 0.5
+
+	inputs := map[string]string{
+		"lines":					header + "\n" + first + "\n" + second + "\n",
+		"lines, truncated":			header + "\n" + first + "\n" + second + "\n" + `{"t":2,"x":5,"y`,
+		"legacy array":				"[\n  " + first + ",\n  " + second + "\n]",
+		"legacy array, unclosed":	"[\n  " + first + ",\n  " + second,
+	}
+
+	dir, err := ioutil.TempDir("", "flogconv")
+	if err != nil {
+		t.Fatalf("%v", err)
+	}
+	defer os.RemoveAll(dir)
+
+	for name, input := range inputs {
+
+		infilename := filepath.Join(dir, "in")
+		outfilename := filepath.Join(dir, "out.json")
+
+		err := ioutil.WriteFile(infilename, []byte(input), 0644)
+		if err != nil {
+			t.Fatalf("%v", err)
+		}
+
+		count, err := convert(infilename, outfilename)
+		if err != nil {
+			t.Errorf("%s: %v", name, err)
+			continue
+		}
+
+		b, err := ioutil.ReadFile(outfilename)
+		if err != nil {
+			t.Fatalf("%v", err)
+		}
+
+		var objects []logging.FlogObject
+
+		err = json.Unmarshal(b, &objects)
+		if err != nil {
+			t.Errorf("%s: output isn't a JSON array: %v", name, err)
+			continue
+		}
+
+		if count != len(expected) || reflect.DeepEqual(objects, expected) == false {
+			t.Errorf("%s: wrote %d objects %v, expected %v", name, count, objects, expected)
+		}
+	}
+}
+
+func TestConvertBadInput(t *testing.T) {
+
+	// A bad record that isn't the last is an error, and no output is made.
+
+	dir, err := ioutil.TempDir("", "flogconv")
+	if err != nil {
+		t.Fatalf("%v", err)
+	}
+	defer os.RemoveAll(dir)
+
+	infilename := filepath.Join(dir, "in")
+	outfilename := filepath.Join(dir, "out.json")
+
+	err = ioutil.WriteFile(infilename, []byte(`{"t":0,"x":1` + "\n" + `{"t":1,"x":2,"y":3}` + "\n"), 0644)
+	if err != nil {
+		t.Fatalf("%v", err)
+	}
+
+	if _, err := convert(infilename, outfilename); err == nil {
+		t.Errorf("no error")
+	}
+
+	if _, err := os.Stat(outfilename); err == nil {
+		t.Errorf("output made anyway")
+	}
+}
@@ -0,0 +1,306 @@
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"html"
+	"image"
+	"image/color"
+	"image/png"
+	"os"
+
+	"../../logging"
+	hal "../../core"
+)
+
+const (
+	HTML_CELL = 14					// Pixels per cell
+	PNG_CELL = 8
+)
+
+var PLAYER_COLOURS = []color.RGBA{
+	{230, 70, 70, 255},
+	{70, 160, 255, 255},
+	{70, 210, 70, 255},
+	{230, 220, 60, 255},
+}
+
+// ------------------------------------------------------------
+
+type grid struct {
+	values			[][]float64
+	min				float64
+	max				float64
+	low				color.RGBA
+	high			color.RGBA
+}
+
+func make_grid(frame *hal.Frame, map_name string) *grid {
+
+	width := frame.Width()
+	height := frame.Height()
+
+	values := make([][]float64, width)
+	for x := 0; x < width; x++ {
+		values[x] = make([]float64, height)
+	}
+
+	for x := 0; x < width; x++ {
+		for y := 0; y < height; y++ {
+			switch map_name {
+			case "halite":			values[x][y] = float64(frame.HaliteAtFast(x, y))
+			case "wealth":			values[x][y] = float64(frame.WealthMap().Values[x][y])
+			case "contest":			values[x][y] = float64(frame.ContestMap().Values[x][y])
+			case "dropoff_dist":	values[x][y] = float64(frame.DropoffDistMap().Values[x][y])
+			case "friendly_dist":	values[x][y] = float64(frame.FriendlyDistMap().Values[x][y])
+			case "enemy_dist":		values[x][y] = float64(frame.EnemyDistMap().Values[x][y])
+			case "inspiration":		values[x][y] = float64(frame.InspirationMap().Values[x][y])
+			case "risk":			values[x][y] = frame.EnemyRiskMap().Values[x][y]
+			}
+		}
+	}
+
+	g := &grid{values: values, min: values[0][0], max: values[0][0]}
+
+	for x := 0; x < width; x++ {
+		for y := 0; y < height; y++ {
+			if values[x][y] < g.min { g.min = values[x][y] }
+			if values[x][y] > g.max { g.max = values[x][y] }
+		}
+	}
+
+	// Colours from the flog layer of the same name, if there is one...
+
+	g.low = parse_colour("#000000")
+	g.high = parse_colour("#ffd700")
+
+	if layer := logging.FlogLayerByName(map_name); layer != nil && layer.Low != "" && layer.High != "" {
+		g.low = parse_colour(layer.Low)
+		g.high = parse_colour(layer.High)
+	} else if map_name == "risk" {
+		g.high = parse_colour("#ff3030")
+	}
+
+	return g
+}
+
+func (self *grid) colour(x, y int) color.RGBA {
+
+	f := 0.0
+	if self.max > self.min {
+		f = (self.values[x][y] - self.min) / (self.max - self.min)
+	}
+
+	mix := func(a, b uint8) uint8 {
+		return uint8(float64(a) + (float64(b) - float64(a)) * f + 0.5)
+	}
+
+	return color.RGBA{mix(self.low.R, self.high.R), mix(self.low.G, self.high.G), mix(self.low.B, self.high.B), 255}
+}
+
+func parse_colour(s string) color.RGBA {
+	var r, g, b uint8
+	fmt.Sscanf(s, "#%02x%02x%02x", &r, &g, &b)
+	return color.RGBA{r, g, b, 255}
+}
+
+func hex(c color.RGBA) string {
+	return fmt.Sprintf("#%02x%02x%02x", c.R, c.G, c.B)
+}
+
+func player_colour(pid int) color.RGBA {
+	return PLAYER_COLOURS[pid % len(PLAYER_COLOURS)]
+}
+
+func target_offset(frame *hal.Frame, ship *hal.Ship) (int, int) {
+
+	// The shortest way to the target, which may go off the edge of the picture.
+
+	dx := hal.Mod(ship.Target().X - ship.X, frame.Width())
+	dy := hal.Mod(ship.Target().Y - ship.Y, frame.Height())
+
+	if dx > frame.Width() / 2 { dx -= frame.Width() }
+	if dy > frame.Height() / 2 { dy -= frame.Height() }
+
+	return dx, dy
+}
+
+// ------------------------------------------------------------
+
+func WriteHTML(filename string, frame *hal.Frame, map_name string) error {
+
+	outfile, err := os.Create(filename)
+	if err != nil {
+		return err
+	}
+	defer outfile.Close()
+
+	w := bufio.NewWriter(outfile)
+
+	g := make_grid(frame, map_name)
+	c := HTML_CELL
+
+	fmt.Fprintf(w, "<!DOCTYPE html>\n<html>\n<head>\n<meta charset=\"utf-8\">\n")
+	fmt.Fprintf(w, "<title>Turn %d - %s</title>\n", frame.Turn(), html.EscapeString(map_name))
+	fmt.Fprintf(w, "<style>body { background-color: #111111; color: #dddddd; font-family: monospace; } svg { display: block; }</style>\n")
+	fmt.Fprintf(w, "</head>\n<body>\n")
+
+	fmt.Fprintf(w, "<p>Turn %d &mdash; %s (range %.2f to %.2f) &mdash; seen by player %d &mdash; budgets", frame.Turn(), html.EscapeString(map_name), g.min, g.max, frame.Pid())
+	for pid := 0; pid < frame.Players(); pid++ {
+		fmt.Fprintf(w, " <span style=\"color: %s\">%d</span>", hex(player_colour(pid)), frame.Budget(pid))
+	}
+	fmt.Fprintf(w, "</p>\n")
+
+	fmt.Fprintf(w, "<svg xmlns=\"http://www.w3.org/2000/svg\" width=\"%d\" height=\"%d\">\n", frame.Width() * c, frame.Height() * c)
+
+	for x := 0; x < frame.Width(); x++ {
+		for y := 0; y < frame.Height(); y++ {
+			fmt.Fprintf(w, "<rect x=\"%d\" y=\"%d\" width=\"%d\" height=\"%d\" fill=\"%s\"><title>%d %d: halite %d, %s %.2f</title></rect>\n",
+				x * c, y * c, c, c, hex(g.colour(x, y)), x, y, frame.HaliteAtFast(x, y), html.EscapeString(map_name), g.values[x][y])
+		}
+	}
+
+	for pid := 0; pid < frame.Players(); pid++ {
+		for _, dropoff := range frame.Dropoffs(pid) {
+			inset := 2
+			if dropoff.Factory {
+				inset = 0
+			}
+			fmt.Fprintf(w, "<rect x=\"%d\" y=\"%d\" width=\"%d\" height=\"%d\" fill=\"none\" stroke=\"%s\" stroke-width=\"2\"><title>%s of player %d</title></rect>\n",
+				dropoff.X * c + inset, dropoff.Y * c + inset, c - inset * 2, c - inset * 2, hex(player_colour(pid)), factory_or_dropoff(dropoff), pid)
+		}
+	}
+
+	for _, ship := range frame.MyShips() {
+		if ship.TargetOK() && ship.SamePlace(ship.Target()) == false {
+			dx, dy := target_offset(frame, ship)
+			fmt.Fprintf(w, "<line x1=\"%d\" y1=\"%d\" x2=\"%d\" y2=\"%d\" stroke=\"%s\" stroke-opacity=\"0.6\"/>\n",
+				ship.X * c + c / 2, ship.Y * c + c / 2, (ship.X + dx) * c + c / 2, (ship.Y + dy) * c + c / 2, hex(player_colour(ship.Owner)))
+		}
+	}
+
+	for pid := 0; pid < frame.Players(); pid++ {
+		for _, ship := range frame.Ships(pid) {
+
+			opacity := 0.4 + 0.6 * float64(ship.Halite) / float64(frame.Constants.MAX_ENERGY)
+
+			target := ""
+			if pid == frame.Pid() && ship.TargetOK() {
+				target = fmt.Sprintf(", target %d %d", ship.Target().X, ship.Target().Y)
+			}
+
+			fmt.Fprintf(w, "<circle cx=\"%d\" cy=\"%d\" r=\"%d\" fill=\"%s\" fill-opacity=\"%.2f\" stroke=\"#ffffff\" stroke-width=\"1\"><title>Ship %d (player %d) at %d %d carrying %d%s</title></circle>\n",
+				ship.X * c + c / 2, ship.Y * c + c / 2, c / 2 - 2, hex(player_colour(pid)), opacity, ship.Sid, pid, ship.X, ship.Y, ship.Halite, target)
+		}
+	}
+
+	fmt.Fprintf(w, "</svg>\n</body>\n</html>\n")
+
+	return w.Flush()
+}
+
+func factory_or_dropoff(dropoff *hal.Dropoff) string {
+	if dropoff.Factory {
+		return "Factory"
+	}
+	return "Dropoff"
+}
+
+// ------------------------------------------------------------
+
+func WritePNG(filename string, frame *hal.Frame, map_name string) error {
+
+	g := make_grid(frame, map_name)
+	c := PNG_CELL
+
+	img := image.NewRGBA(image.Rect(0, 0, frame.Width() * c, frame.Height() * c))
+
+	for x := 0; x < frame.Width(); x++ {
+		for y := 0; y < frame.Height(); y++ {
+			fill_rect(img, x * c, y * c, c, c, g.colour(x, y))
+		}
+	}
+
+	for pid := 0; pid < frame.Players(); pid++ {
+		for _, dropoff := range frame.Dropoffs(pid) {
+			inset := 1
+			if dropoff.Factory {
+				inset = 0
+			}
+			outline_rect(img, dropoff.X * c + inset, dropoff.Y * c + inset, c - inset * 2, c - inset * 2, player_colour(pid))
+		}
+	}
+
+	for _, ship := range frame.MyShips() {
+		if ship.TargetOK() && ship.SamePlace(ship.Target()) == false {
+			dx, dy := target_offset(frame, ship)
+			draw_line(img, ship.X * c + c / 2, ship.Y * c + c / 2, (ship.X + dx) * c + c / 2, (ship.Y + dy) * c + c / 2, player_colour(ship.Owner))
+		}
+	}
+
+	for pid := 0; pid < frame.Players(); pid++ {
+		for _, ship := range frame.Ships(pid) {
+			fill_rect(img, ship.X * c + 2, ship.Y * c + 2, c - 4, c - 4, player_colour(pid))
+			if ship.Halite >= frame.Constants.MAX_ENERGY / 2 {
+				fill_rect(img, ship.X * c + c / 2 - 1, ship.Y * c + c / 2 - 1, 2, 2, color.RGBA{255, 255, 255, 255})
+			}
+		}
+	}
+
+	outfile, err := os.Create(filename)
+	if err != nil {
+		return err
+	}
+	defer outfile.Close()
+
+	return png.Encode(outfile, img)
+}
+
+func fill_rect(img *image.RGBA, x, y, w, h int, col color.RGBA) {
+	for i := x; i < x + w; i++ {
+		for j := y; j < y + h; j++ {
+			img.SetRGBA(i, j, col)			// Out of bounds is ignored
+		}
+	}
+}
+
+func outline_rect(img *image.RGBA, x, y, w, h int, col color.RGBA) {
+	for i := x; i < x + w; i++ {
+		img.SetRGBA(i, y, col)
+		img.SetRGBA(i, y + h - 1, col)
+	}
+	for j := y; j < y + h; j++ {
+		img.SetRGBA(x, j, col)
+		img.SetRGBA(x + w - 1, j, col)
+	}
+}
+
+func draw_line(img *image.RGBA, x0, y0, x1, y1 int, col color.RGBA) {
+
+	// Bresenham.
+
+	dx := hal.Abs(x1 - x0)
+	dy := -hal.Abs(y1 - y0)
+
+	sx, sy := 1, 1
+	if x0 > x1 { sx = -1 }
+	if y0 > y1 { sy = -1 }
+
+	err := dx + dy
+
+	for {
+		img.SetRGBA(x0, y0, col)
+		if x0 == x1 && y0 == y1 {
+			return
+		}
+		e2 := 2 * err
+		if e2 >= dy {
+			err += dy
+			x0 += sx
+		}
+		if e2 <= dx {
+			err += dx
+			y0 += sy
+		}
+	}
+}
@@ -0,0 +1,156 @@
+package main
+
+// Renders one turn of a game -- the halite, or any of the core maps -- with
+// ships, dropoffs and targets drawn on top, to a self-contained HTML file
+// (with tooltips) or a PNG. The game comes from a saved engine input, such
+// as misc_scripts/inputsaver.go writes; with -sim, the game is instead played
+// on from wherever the input ends, by our own AI for every player.
+//
+//		render -turn 150 -map contest -pid 1 -o contest.html inputsaver.txt
+//		render -sim -turn 300 -o wealth.png -map wealth initial_map.txt
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"strings"
+
+	"../../ai"
+	"../../logging"
+	hal "../../core"
+)
+
+var MAP_NAMES = []string{"halite", "wealth", "contest", "dropoff_dist", "friendly_dist", "enemy_dist", "inspiration", "risk"}
+
+func main() {
+
+	var turn int
+	var map_name string
+	var pid int
+	var sim bool
+	var outfilename string
+
+	flag.IntVar(&turn, "turn", -1, "turn to render (default: the last available)")
+	flag.StringVar(&map_name, "map", "halite", "what to colour the cells by: " + strings.Join(MAP_NAMES, ", "))
+	flag.IntVar(&pid, "pid", 0, "player whose point of view the maps and targets use")
+	flag.BoolVar(&sim, "sim", false, "play on by simulation once the input runs out")
+	flag.StringVar(&outfilename, "o", "render.html", "output file; .png for an image, anything else for HTML")
+	flag.Parse()
+
+	if flag.NArg() < 1 {
+		fmt.Printf("Usage: %s [flags] <input file>\n", os.Args[0])
+		flag.PrintDefaults()
+		os.Exit(1)
+	}
+
+	valid := false
+	for _, name := range MAP_NAMES {
+		if name == map_name {
+			valid = true
+		}
+	}
+	if valid == false {
+		fmt.Printf("Unknown map %q; choose from %s\n", map_name, strings.Join(MAP_NAMES, ", "))
+		os.Exit(1)
+	}
+
+	infile, err := os.Open(flag.Arg(0))
+	if err != nil {
+		fmt.Printf("%v\n", err)
+		os.Exit(1)
+	}
+	defer infile.Close()
+
+	logging.Suppress()
+
+	frame := load(infile, turn, sim)
+
+	if pid < 0 || pid >= frame.Players() {
+		fmt.Printf("No player %d in a %d player game\n", pid, frame.Players())
+		os.Exit(1)
+	}
+
+	// Let the AI choose targets for this turn...
+
+	for n := 0; n < frame.Players(); n++ {
+		ai.Step(frame, n, true, nil)
+	}
+
+	frame.SetPid(pid)
+
+	if turn >= 0 && frame.Turn() != turn {
+		fmt.Printf("Input ended at turn %d; rendering that instead of turn %d\n", frame.Turn(), turn)
+	}
+
+	if strings.HasSuffix(strings.ToLower(outfilename), ".png") {
+		err = WritePNG(outfilename, frame, map_name)
+	} else {
+		err = WriteHTML(outfilename, frame, map_name)
+	}
+
+	if err != nil {
+		fmt.Printf("%v\n", err)
+		os.Exit(1)
+	}
+
+	fmt.Printf("Rendered turn %d (%s, player %d) to %s\n", frame.Turn(), map_name, pid, outfilename)
+}
+
+func load(infile *os.File, turn int, sim bool) *hal.Frame {
+
+	// Reads the game up to <turn> (or as far as it goes, if turn < 0).
+
+	hal.SetInput(infile)
+
+	frame := hal.NewGame()
+	frame.PrePreParse()
+	frame.PreParse()
+
+	input_ok := true
+
+	for turn < 0 || frame.Turn() < turn {
+
+		if frame.Turn() >= frame.Constants.MAX_TURNS - 1 {
+			break
+		}
+
+		if input_ok {
+
+			backup := frame.Remake()
+
+			ai.Step(frame, frame.Pid(), true, nil)			// Keeps our AI state (routes etc) going
+
+			if try_parse(frame) {
+				continue
+			}
+
+			frame = backup
+			input_ok = false
+		}
+
+		if sim == false || turn < 0 {
+			break
+		}
+
+		for n := 0; n < frame.Players(); n++ {
+			ai.Step(frame, n, true, nil)
+		}
+		frame = frame.SimGen()
+	}
+
+	return frame
+}
+
+func try_parse(frame *hal.Frame) (ok bool) {
+
+	// Parse() panics at the end of input.
+
+	defer func() {
+		if p := recover(); p != nil {
+			ok = false
+		}
+	}()
+
+	frame.Parse()
+	return true
+}
@@ -0,0 +1,276 @@
+package main
+
+// Aggregate stats for our bot over a directory of replays (compressed or not,
+// though compressed ones need zstd on PATH), grouped by map size, player count
+// and opponent (ignoring their version). Replaces the parts of
+// misc_scripts/lunariz.py that needed the halite.io API.
+//
+//		replaystats -name Fohristiwhirl replays/
+
+import (
+	"flag"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	hal "../../core"
+)
+
+type Sample struct {					// One of our players in one game
+	Rank				int
+	Players				int
+	Score				float64			// As lunariz.py: 100 for a win, 0 for last
+	Production			int
+	MinedPerShip		float64
+	Collisions			int
+	SelfCollisions		int
+	Dropoffs			int
+	FirstDropoff		int				// -1 if none
+	SpawnCutoff			int				// Turn of the last spawn, -1 if none
+}
+
+type Group struct {
+	Key					string
+	Samples				[]*Sample
+}
+
+func main() {
+
+	var name string
+	flag.StringVar(&name, "name", "Fohristiwhirl", "our players are those whose names contain this")
+	flag.Parse()
+
+	if flag.NArg() < 1 {
+		fmt.Printf("Usage: %s [flags] <replay directory>\n", os.Args[0])
+		flag.PrintDefaults()
+		os.Exit(1)
+	}
+
+	dir := flag.Arg(0)
+
+	files, err := ioutil.ReadDir(dir)
+	if err != nil {
+		fmt.Printf("%v\n", err)
+		os.Exit(1)
+	}
+
+	groups := make(map[string]*Group)
+	games := 0
+
+	add := func(key string, sample *Sample) {
+		if groups[key] == nil {
+			groups[key] = &Group{Key: key}
+		}
+		groups[key].Samples = append(groups[key].Samples, sample)
+	}
+
+	for _, file := range files {
+
+		if file.IsDir() {
+			continue
+		}
+
+		replay, err := hal.LoadReplay(filepath.Join(dir, file.Name()))
+		if err == hal.ErrNoZstd {
+			fmt.Printf("%s: %v\n", file.Name(), err)		// No point going on
+			os.Exit(1)
+		}
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "%s: %v\n", file.Name(), err)
+			continue
+		}
+
+		games++
+
+		for _, player := range replay.Players {
+
+			if strings.Contains(player.Name, name) == false {
+				continue
+			}
+
+			sample := make_sample(replay, player.PlayerId)
+			if sample == nil {
+				continue
+			}
+
+			add("all", sample)
+			add(fmt.Sprintf("size %d", replay.ProductionMap.Width), sample)
+			add(fmt.Sprintf("%d players", replay.NumberOfPlayers), sample)
+
+			// Opponents are grouped by name without version, each counted once per
+			// game even if it had several players in it...
+
+			seen := make(map[string]bool)
+
+			for _, opponent := range replay.Players {
+				base := base_name(opponent.Name)
+				if strings.Contains(opponent.Name, name) == false && seen[base] == false {
+					seen[base] = true
+					add("vs " + base, sample)
+				}
+			}
+		}
+	}
+
+	fmt.Printf("%d replays read\n\n", games)
+
+	var keys []string
+	for key, _ := range groups {
+		keys = append(keys, key)
+	}
+
+	sort.Slice(keys, func(a, b int) bool {
+		return group_order(keys[a]) < group_order(keys[b]) || (group_order(keys[a]) == group_order(keys[b]) && keys[a] < keys[b])
+	})
+
+	fmt.Printf("%-28s %5s %6s   %-23s %7s %7s %6s %6s %6s %8s %7s\n",
+		"", "games", "score", "ranks 1/2/3/4 (%)", "prod", "mined/s", "coll", "self", "drops", "1st drop", "spawn<")
+
+	for _, key := range keys {
+		print_group(groups[key])
+	}
+}
+
+func base_name(name string) string {
+
+	// "teccles v91" --> "teccles"
+
+	fields := strings.Fields(name)
+
+	if len(fields) > 1 {
+		last := fields[len(fields) - 1]
+		if len(last) > 1 && (last[0] == 'v' || last[0] == 'V') && last[1] >= '0' && last[1] <= '9' {
+			return strings.Join(fields[:len(fields) - 1], " ")
+		}
+	}
+
+	return name
+}
+
+func group_order(key string) int {
+	switch {
+	case key == "all":						return 0
+	case strings.HasPrefix(key, "size "):	return 1
+	case strings.HasSuffix(key, " players"):	return 2
+	}
+	return 3
+}
+
+func make_sample(replay *hal.Replay, pid int) *Sample {
+
+	var stats *hal.PlayerStatistics
+
+	for _, s := range replay.GameStatistics.PlayerStatistics {
+		if s.PlayerId == pid {
+			stats = s
+		}
+	}
+
+	if stats == nil {
+		return nil
+	}
+
+	ret := &Sample{
+		Rank:				stats.Rank,
+		Players:			replay.NumberOfPlayers,
+		Production:			stats.FinalProduction,
+		Collisions:			stats.AllCollisions,
+		SelfCollisions:		stats.SelfCollisions,
+		FirstDropoff:		-1,
+		SpawnCutoff:		-1,
+	}
+
+	if replay.NumberOfPlayers > 1 {
+		ret.Score = 100 - 100 * float64(stats.Rank - 1) / float64(replay.NumberOfPlayers - 1)
+	}
+
+	// Spawns and dropoffs come from the events, which every replay has...
+
+	spawns := 0
+
+	for turn, frame := range replay.FullFrames {
+		for _, event := range frame.Events {
+
+			if event.OwnerId == nil || *event.OwnerId != pid {
+				continue
+			}
+
+			switch event.Type {
+			case "spawn":
+				spawns++
+				ret.SpawnCutoff = turn
+			case "construct":
+				ret.Dropoffs++
+				if ret.FirstDropoff == -1 {
+					ret.FirstDropoff = turn
+				}
+			}
+		}
+	}
+
+	if spawns > 0 {
+		ret.MinedPerShip = float64(stats.TotalMined) / float64(spawns)
+	}
+
+	return ret
+}
+
+func print_group(group *Group) {
+
+	n := float64(len(group.Samples))
+
+	var score, production, mined, collisions, self_collisions, dropoffs float64
+	var first_dropoff, first_dropoff_count, cutoff, cutoff_count float64
+	ranks := make([]int, 4)
+
+	for _, s := range group.Samples {
+
+		score += s.Score
+		production += float64(s.Production)
+		mined += s.MinedPerShip
+		collisions += float64(s.Collisions)
+		self_collisions += float64(s.SelfCollisions)
+		dropoffs += float64(s.Dropoffs)
+
+		if s.Rank >= 1 && s.Rank <= 4 {
+			ranks[s.Rank - 1]++
+		}
+
+		if s.FirstDropoff >= 0 {
+			first_dropoff += float64(s.FirstDropoff)
+			first_dropoff_count++
+		}
+
+		if s.SpawnCutoff >= 0 {
+			cutoff += float64(s.SpawnCutoff)
+			cutoff_count++
+		}
+	}
+
+	var rank_strings []string
+	for _, count := range ranks {
+		rank_strings = append(rank_strings, fmt.Sprintf("%3.0f", 100 * float64(count) / n))
+	}
+
+	first_dropoff_string := "-"
+	if first_dropoff_count > 0 {
+		first_dropoff_string = fmt.Sprintf("%.0f", first_dropoff / first_dropoff_count)
+	}
+
+	cutoff_string := "-"
+	if cutoff_count > 0 {
+		cutoff_string = fmt.Sprintf("%.0f", cutoff / cutoff_count)
+	}
+
+	key := group.Key
+	if len(key) > 28 {
+		key = key[:28]
+	}
+
+	fmt.Printf("%-28s %5d %6.1f   %-23s %7.0f %7.0f %6.1f %6.1f %6.1f %8s %7s\n",
+		key, len(group.Samples), score / n, strings.Join(rank_strings, " "),
+		production / n, mined / n, collisions / n, self_collisions / n, dropoffs / n, first_dropoff_string, cutoff_string)
+}
@@ -0,0 +1,42 @@
+package main
+
+// Prints the decision trace of one ship, as written by the bot's -trace flag.
+//
+//		trace logs/trace-0.jsonl 17
+
+import (
+	"fmt"
+	"os"
+	"strconv"
+
+	"../../ai"
+)
+
+func main() {
+
+	if len(os.Args) < 3 {
+		fmt.Printf("Usage: %s <tracefile> <ship id>\n", os.Args[0])
+		os.Exit(1)
+	}
+
+	sid, err := strconv.Atoi(os.Args[2])
+	if err != nil {
+		fmt.Printf("Bad ship id: %v\n", os.Args[2])
+		os.Exit(1)
+	}
+
+	records, err := ai.ReadTrace(os.Args[1], sid)
+	if err != nil {
+		fmt.Printf("%v\n", err)
+		os.Exit(1)
+	}
+
+	if len(records) == 0 {
+		fmt.Printf("No records for ship %d\n", sid)
+		return
+	}
+
+	for _, r := range records {
+		fmt.Printf("%v\n", r)
+	}
+}